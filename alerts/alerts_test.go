@@ -0,0 +1,122 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+func failedMetrics(percent float64) *postage_app.Metrics {
+	metrics := new(postage_app.Metrics)
+	metrics.Hour = new(postage_app.Metric)
+	metrics.Hour.Failed = &postage_app.MetricStatistic{CurrentPercent: percent, Valid: postage_app.MetricValid{CurrentPercent: true}}
+	return metrics
+}
+
+func TestParseRule(t *testing.T) {
+	rule, err := ParseRule("", "date.spammed.diff_percent>=50.5")
+	if err != nil || rule.Period != "date" || rule.Statistic != "spammed" || rule.Field != "diff_percent" ||
+		rule.Op != ">=" || rule.Threshold != 50.5 || rule.Name != "date.spammed.diff_percent>=50.5" {
+		t.Log(rule, err)
+		t.Fail()
+	}
+	if rule.String() != "date.spammed.diff_percent >= 50.5" {
+		t.Log(rule.String())
+		t.Fail()
+	}
+	for _, expr := range []string{"hour.failed > 5", "hour.failed.current_percent ~ 5", "hour.failed.current_percent > x",
+		"hour.faild.current_percent > 5", "hours.failed.current_percent > 5", "hour.failed.current_prcent > 5"} {
+		if _, err := ParseRule("", expr); err == nil {
+			t.Log(expr)
+			t.Fail()
+		}
+	}
+
+	rule, err = ParseExtraRule("", "quarter.bounced.current_percent > 5")
+	if err != nil || rule.Period != "quarter" || rule.Statistic != "bounced" {
+		t.Log(rule, err)
+		t.Fail()
+	}
+	if _, err := ParseExtraRule("", "quarter.bounced.current_prcent > 5"); err == nil {
+		t.Fail()
+	}
+}
+
+func TestEvaluatorHysteresis(t *testing.T) {
+	rule := MustParseRule("failures", "hour.failed.current_percent > 5")
+	rule.FireAfter = 2
+	rule.ResolveAfter = 2
+	rule.Margin = 2
+
+	var notified []*Alert
+	evaluator := NewEvaluator(nil, rule)
+	evaluator.AddNotifier(NotifierFunc(func(ctx context.Context, alert *Alert) error {
+		notified = append(notified, alert)
+		return nil
+	}))
+
+	expected := map[int]State{3: StateFiring, 8: StateResolved}
+	for i, percent := range []float64{6, 4, 7, 8, 4.5, 2, 6, 2, 1} {
+		alerts, err := evaluator.Evaluate(context.Background(), failedMetrics(percent))
+		if err != nil {
+			t.Fatal(err)
+		}
+		state, ok := expected[i]
+		if ok != (len(alerts) == 1) || (ok && alerts[0].State != state) {
+			t.Log(i, percent, alerts)
+			t.Fail()
+		}
+	}
+	if len(notified) != 2 || notified[1].Value != 1 || len(evaluator.Firing()) != 0 {
+		t.Log(notified)
+		t.Fail()
+	}
+}
+
+func TestEvaluatorSkipsMissingValues(t *testing.T) {
+	evaluator := NewEvaluator(nil, MustParseRule("", "week.spammed.current_percent > 1"))
+	alerts, err := evaluator.Evaluate(context.Background(), failedMetrics(90))
+	if err != nil || len(alerts) != 0 {
+		t.Log(alerts, err)
+		t.Fail()
+	}
+}
+
+func TestCheckWithWebhook(t *testing.T) {
+	received := make(chan *Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		alert := new(Alert)
+		json.NewDecoder(r.Body).Decode(alert)
+		received <- alert
+	}))
+	defer server.Close()
+
+	cl, _ := postage_app.NewClient("alerts-key", postage_app.WithSandbox(postage_app.NewSandbox(nil)))
+	message := new(postage_app.Message)
+	message.Text = "hello"
+	message.Recipients = []*postage_app.Recipient{{Email: "test@null.postageapp.com"}}
+	cl.SendMessage(message)
+
+	evaluator := NewEvaluator(cl, MustParseRule("delivered", "hour.delivered.current_value >= 1"))
+	evaluator.AddNotifier(NewWebhookNotifier(server.URL))
+	evaluator.AddNotifier(new(LogNotifier))
+	evaluator.now = func() time.Time { return time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC) }
+
+	if _, err := evaluator.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	alert := <-received
+	if alert.Rule != "delivered" || alert.State != StateFiring || alert.Value != 1 {
+		t.Log(alert)
+		t.Fail()
+	}
+	if firing := evaluator.Firing(); len(firing) != 1 || firing[0] != "delivered" {
+		t.Log(firing)
+		t.Fail()
+	}
+}
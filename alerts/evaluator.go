@@ -0,0 +1,162 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+	"github.com/postageapp/postageapp-go/metrics"
+)
+
+type State string
+
+const (
+	StateFiring   State = "firing"
+	StateResolved State = "resolved"
+)
+
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Expr      string    `json:"expr"`
+	State     State     `json:"state"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Since     time.Time `json:"since"`
+	Time      time.Time `json:"time"`
+}
+
+type ruleState struct {
+	firing   bool
+	breaches int
+	clears   int
+	since    time.Time
+}
+
+type Evaluator struct {
+	Client    metrics.Fetcher
+	Rules     []*Rule
+	Notifiers []Notifier
+	Interval  time.Duration
+	OnError   func(error)
+
+	mu     sync.Mutex
+	states map[*Rule]*ruleState
+	now    func() time.Time
+}
+
+func NewEvaluator(client metrics.Fetcher, rules ...*Rule) *Evaluator {
+	evaluator := new(Evaluator)
+	evaluator.Client = client
+	evaluator.Rules = rules
+	evaluator.Interval = 5 * time.Minute
+	return evaluator
+}
+
+func (evaluator *Evaluator) AddNotifier(notifier Notifier) {
+	evaluator.Notifiers = append(evaluator.Notifiers, notifier)
+}
+
+func (evaluator *Evaluator) Firing() []string {
+	evaluator.mu.Lock()
+	defer evaluator.mu.Unlock()
+	var names []string
+	for _, rule := range evaluator.Rules {
+		if state := evaluator.states[rule]; state != nil && state.firing {
+			names = append(names, rule.Name)
+		}
+	}
+	return names
+}
+
+func (evaluator *Evaluator) Evaluate(ctx context.Context, data *postage_app.Metrics) ([]*Alert, error) {
+	now := time.Now()
+	if evaluator.now != nil {
+		now = evaluator.now()
+	}
+
+	var alerts []*Alert
+	evaluator.mu.Lock()
+	if evaluator.states == nil {
+		evaluator.states = make(map[*Rule]*ruleState)
+	}
+	for _, rule := range evaluator.Rules {
+		value, ok := rule.Value(data)
+		if !ok {
+			continue
+		}
+		state := evaluator.states[rule]
+		if state == nil {
+			state = new(ruleState)
+			evaluator.states[rule] = state
+		}
+		if alert := state.observe(rule, value, now); alert != nil {
+			alerts = append(alerts, alert)
+		}
+	}
+	evaluator.mu.Unlock()
+
+	var errs []error
+	for _, alert := range alerts {
+		for _, notifier := range evaluator.Notifiers {
+			if err := notifier.Notify(ctx, alert); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return alerts, errors.Join(errs...)
+}
+
+func (state *ruleState) observe(rule *Rule, value float64, now time.Time) *Alert {
+	if !state.firing {
+		if !rule.breached(value, rule.Threshold) {
+			state.breaches = 0
+			return nil
+		}
+		state.breaches++
+		if state.breaches < max(rule.FireAfter, 1) {
+			return nil
+		}
+		state.firing = true
+		state.breaches = 0
+		state.clears = 0
+		state.since = now
+		return &Alert{rule.Name, rule.String(), StateFiring, value, rule.Threshold, now, now}
+	}
+
+	if rule.breached(value, rule.clearThreshold()) {
+		state.clears = 0
+		return nil
+	}
+	state.clears++
+	if state.clears < max(rule.ResolveAfter, 1) {
+		return nil
+	}
+	state.firing = false
+	state.clears = 0
+	return &Alert{rule.Name, rule.String(), StateResolved, value, rule.Threshold, state.since, now}
+}
+
+func (evaluator *Evaluator) Check(ctx context.Context) ([]*Alert, error) {
+	response, err := evaluator.Client.GetMetricsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.Evaluate(ctx, response.Data)
+}
+
+func (evaluator *Evaluator) Run(ctx context.Context) {
+	ticker := time.NewTicker(evaluator.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := evaluator.Check(ctx); err != nil && evaluator.OnError != nil && ctx.Err() == nil {
+			evaluator.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, alert *Alert) error
+}
+
+type NotifierFunc func(ctx context.Context, alert *Alert) error
+
+func (f NotifierFunc) Notify(ctx context.Context, alert *Alert) error {
+	return f(ctx, alert)
+}
+
+type WebhookNotifier struct {
+	Url        string
+	HttpClient *http.Client
+	Header     http.Header
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	notifier := new(WebhookNotifier)
+	notifier.Url = url
+	return notifier
+}
+
+func (notifier *WebhookNotifier) Notify(ctx context.Context, alert *Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", notifier.Url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, values := range notifier.Header {
+		request.Header[name] = values
+	}
+
+	httpClient := notifier.HttpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("alerts: webhook %s returned HTTP status %d", notifier.Url, response.StatusCode)
+	}
+	return nil
+}
+
+type LogNotifier struct {
+	Logger *slog.Logger
+}
+
+func (notifier *LogNotifier) Notify(ctx context.Context, alert *Alert) error {
+	logger := notifier.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	level := slog.LevelWarn
+	if alert.State == StateResolved {
+		level = slog.LevelInfo
+	}
+	logger.Log(ctx, level, "postageapp: alert "+string(alert.State), "rule", alert.Rule, "expr", alert.Expr,
+		"value", alert.Value, "threshold", alert.Threshold)
+	return nil
+}
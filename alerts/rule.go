@@ -0,0 +1,140 @@
+package alerts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	postage_app "github.com/postageapp/postageapp-go"
+	"github.com/postageapp/postageapp-go/metrics"
+)
+
+var rulePattern = regexp.MustCompile(`^\s*([a-z_]+)\.([a-z_]+)\.([a-z_]+)\s*(>=|<=|==|!=|>|<)\s*(-?[0-9]+(?:\.[0-9]+)?)\s*$`)
+
+type Rule struct {
+	Name         string
+	Period       string
+	Statistic    string
+	Field        string
+	Op           string
+	Threshold    float64
+	FireAfter    int
+	ResolveAfter int
+	Margin       float64
+}
+
+type RuleError struct {
+	Expr    string
+	Message string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("alerts: rule %q: %s", e.Expr, e.Message)
+}
+
+func ParseRule(name string, expr string) (*Rule, error) {
+	return parseRule(name, expr, false)
+}
+
+func ParseExtraRule(name string, expr string) (*Rule, error) {
+	return parseRule(name, expr, true)
+}
+
+func contains(names []string, name string) bool {
+	for _, known := range names {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+func parseRule(name string, expr string, extra bool) (*Rule, error) {
+	match := rulePattern.FindStringSubmatch(expr)
+	if match == nil {
+		return nil, &RuleError{expr, "expected <period>.<statistic>.<field> <op> <number>"}
+	}
+	threshold, err := strconv.ParseFloat(match[5], 64)
+	if err != nil {
+		return nil, &RuleError{expr, err.Error()}
+	}
+	if !extra && !contains(postage_app.MetricPeriods, match[1]) {
+		return nil, &RuleError{expr, fmt.Sprintf("unknown period %q", match[1])}
+	}
+	if !extra && !contains(postage_app.MetricStatistics, match[2]) {
+		return nil, &RuleError{expr, fmt.Sprintf("unknown statistic %q", match[2])}
+	}
+	if !contains(metrics.Fields, match[3]) {
+		return nil, &RuleError{expr, fmt.Sprintf("unknown field %q", match[3])}
+	}
+
+	rule := new(Rule)
+	rule.Name = name
+	if rule.Name == "" {
+		rule.Name = expr
+	}
+	rule.Period = match[1]
+	rule.Statistic = match[2]
+	rule.Field = match[3]
+	rule.Op = match[4]
+	rule.Threshold = threshold
+	rule.FireAfter = 1
+	rule.ResolveAfter = 1
+	return rule, nil
+}
+
+func MustParseRule(name string, expr string) *Rule {
+	rule, err := ParseRule(name, expr)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+func (rule *Rule) String() string {
+	return fmt.Sprintf("%s.%s.%s %s %s", rule.Period, rule.Statistic, rule.Field, rule.Op,
+		strconv.FormatFloat(rule.Threshold, 'g', -1, 64))
+}
+
+func (rule *Rule) Value(metrics *postage_app.Metrics) (float64, bool) {
+	if metrics == nil {
+		return 0, false
+	}
+	metric := metrics.Period(rule.Period)
+	if metric == nil {
+		return 0, false
+	}
+	statistic := metric.Statistic(rule.Statistic)
+	if statistic == nil {
+		return 0, false
+	}
+	return statistic.Field(rule.Field)
+}
+
+func (rule *Rule) breached(value float64, threshold float64) bool {
+	switch rule.Op {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	case "==":
+		return value == threshold
+	case "!=":
+		return value != threshold
+	}
+	return false
+}
+
+func (rule *Rule) clearThreshold() float64 {
+	switch rule.Op {
+	case ">", ">=":
+		return rule.Threshold - rule.Margin
+	case "<", "<=":
+		return rule.Threshold + rule.Margin
+	}
+	return rule.Threshold
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	postage_app "github.com/postageapp/postageapp-go"
+	"github.com/postageapp/postageapp-go/smtprelay"
+)
+
+func main() {
+	listen := flag.String("listen", smtprelay.DefaultAddr, "address to accept SMTP connections on")
+	hostname := flag.String("hostname", "", "hostname announced in the SMTP greeting")
+	baseUrl := flag.String("base-url", "", "PostageApp API base URL")
+	username := flag.String("username", "", "require AUTH with this username")
+	allowDomains := flag.String("allow-domains", "", "comma separated list of allowed sender domains")
+	certFile := flag.String("tls-cert", "", "certificate file for STARTTLS")
+	keyFile := flag.String("tls-key", "", "key file for STARTTLS")
+	maxBytes := flag.Int64("max-message-bytes", smtprelay.DefaultMaxMessageBytes, "maximum accepted message size")
+	sendTimeout := flag.Duration("send-timeout", smtprelay.DefaultSendTimeout, "maximum time to wait for PostageApp to accept a message")
+	flag.Parse()
+
+	config, err := postage_app.ConfigFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var opts []postage_app.Option
+	if *baseUrl != "" {
+		opts = append(opts, postage_app.WithBaseURL(*baseUrl))
+	}
+	client, err := config.NewClient(opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := new(smtprelay.Server)
+	server.Addr = *listen
+	server.Hostname = *hostname
+	server.Client = client
+	server.MaxMessageBytes = *maxBytes
+	server.SendTimeout = *sendTimeout
+
+	if *username != "" {
+		password := os.Getenv("POSTAGE_SMTP_PASSWORD")
+		if password == "" {
+			log.Fatal("POSTAGE_SMTP_PASSWORD must be set when -username is given")
+		}
+		server.Authenticate = smtprelay.StaticAuth(*username, password)
+	}
+
+	if *allowDomains != "" {
+		for _, domain := range strings.Split(*allowDomains, ",") {
+			if domain = strings.TrimSpace(domain); domain != "" {
+				server.AllowedDomains = append(server.AllowedDomains, domain)
+			}
+		}
+	}
+
+	if *certFile != "" || *keyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Close()
+	}()
+
+	log.Printf("postage-smtp listening on %s", *listen)
+	if err := server.ListenAndServe(); err != nil && err != smtprelay.ErrServerClosed {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type SeriesPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+type Series struct {
+	Name      string        `json:"name"`
+	Period    string        `json:"period"`
+	Statistic string        `json:"statistic"`
+	Field     string        `json:"field"`
+	Points    []SeriesPoint `json:"points"`
+}
+
+func Group(points []Point) []*Series {
+	index := make(map[string]*Series)
+	var series []*Series
+	for _, point := range points {
+		name := point.Series()
+		s, ok := index[name]
+		if !ok {
+			s = &Series{Name: name, Period: point.Period, Statistic: point.Statistic, Field: point.Field}
+			index[name] = s
+			series = append(series, s)
+		}
+		s.Points = append(s.Points, SeriesPoint{point.Time, point.Value})
+	}
+
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].Name < series[j].Name
+	})
+	for _, s := range series {
+		sort.SliceStable(s.Points, func(i, j int) bool {
+			return s.Points[i].Time.Before(s.Points[j].Time)
+		})
+	}
+	return series
+}
+
+func WriteCSV(w io.Writer, points []Point) error {
+	writer := csv.NewWriter(w)
+	writer.Write(csvHeader)
+	for _, point := range points {
+		writer.Write(csvRecord(point))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteJSON(w io.Writer, points []Point) error {
+	series := Group(points)
+	if series == nil {
+		series = []*Series{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(series)
+}
+
+var openMetricsHelp = map[string]string{
+	"current_percent":  "Share of messages in the current period.",
+	"previous_percent": "Share of messages in the previous period.",
+	"diff_percent":     "Change in share between the previous and current period.",
+	"current_value":    "Number of messages in the current period.",
+	"previous_value":   "Number of messages in the previous period.",
+}
+
+func WriteOpenMetrics(w io.Writer, points []Point) error {
+	byField := make(map[string][]*Series)
+	for _, s := range Group(points) {
+		byField[s.Field] = append(byField[s.Field], s)
+	}
+
+	fields := make([]string, 0, len(byField))
+	for field := range byField {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		name := "postageapp_" + field
+		help := openMetricsHelp[field]
+		if help == "" {
+			help = "PostageApp metric " + field + "."
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s gauge\n# HELP %s %s\n", name, name, help); err != nil {
+			return err
+		}
+		for _, s := range byField[field] {
+			for _, point := range s.Points {
+				_, err := fmt.Fprintf(w, "%s{period=%q,statistic=%q} %s %s\n", name, s.Period, s.Statistic,
+					strconv.FormatFloat(point.Value, 'g', -1, 64), openMetricsTimestamp(point.Time))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+func openMetricsTimestamp(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', -1, 64)
+}
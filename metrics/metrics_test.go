@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+func InitPoller(t *testing.T, store Store) (*Poller, *time.Time) {
+	cl, err := postage_app.NewClient("metrics-key", postage_app.WithSandbox(postage_app.NewSandbox(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := new(postage_app.Message)
+	message.Text = "hello"
+	message.Recipients = []*postage_app.Recipient{{Email: "test@null.postageapp.com"}}
+	if _, err := cl.SendMessage(message); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	poller := NewPoller(cl, store, time.Minute)
+	poller.now = func() time.Time { return now }
+	return poller, &now
+}
+
+func TestPollerFlattensValidFields(t *testing.T) {
+	store := NewMemoryStore(0)
+	poller, _ := InitPoller(t, store)
+
+	points, err := poller.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 4*8*5 || store.Len() != len(points) {
+		t.Log(len(points), store.Len())
+		t.Fail()
+	}
+
+	found := false
+	for _, point := range points {
+		if point.Series() == "hour.delivered.current_value" {
+			found = point.Value == 1
+		}
+	}
+	if !found {
+		t.Log(points)
+		t.Fail()
+	}
+}
+
+func TestMemoryStoreRingBuffer(t *testing.T) {
+	store := NewMemoryStore(3)
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		store.Append([]Point{{base.Add(time.Duration(i) * time.Hour), "hour", "failed", "current_value", float64(i)}})
+	}
+
+	points, _ := store.Points(time.Time{}, time.Time{})
+	if len(points) != 3 || points[0].Value != 2 || points[2].Value != 4 {
+		t.Log(points)
+		t.Fail()
+	}
+	points, _ = store.Points(base.Add(3*time.Hour), base.Add(4*time.Hour))
+	if len(points) != 1 || points[0].Value != 3 {
+		t.Log(points)
+		t.Fail()
+	}
+}
+
+func TestFileStores(t *testing.T) {
+	dir := t.TempDir()
+	for _, store := range []*FileStore{NewCSVStore(filepath.Join(dir, "metrics.csv")), NewJSONLinesStore(filepath.Join(dir, "metrics.jsonl"))} {
+		poller, now := InitPoller(t, store)
+		first, _ := poller.Poll(context.Background())
+		*now = now.Add(time.Hour)
+		poller.Poll(context.Background())
+
+		points, err := store.Points(time.Time{}, time.Time{})
+		if err != nil || len(points) != 2*len(first) {
+			t.Log(store.Format, len(points), err)
+			t.Fail()
+			continue
+		}
+		if points[0] != first[0] {
+			t.Log(store.Format, points[0], first[0])
+			t.Fail()
+		}
+		later, _ := store.Points(*now, time.Time{})
+		if len(later) != len(first) {
+			t.Log(store.Format, len(later))
+			t.Fail()
+		}
+	}
+}
+
+func TestExporters(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	points := []Point{
+		{base.Add(time.Hour), "hour", "failed", "current_percent", 2.5},
+		{base, "hour", "failed", "current_percent", 1},
+		{base, "date", "delivered", "current_value", 40},
+	}
+
+	var out bytes.Buffer
+	WriteCSV(&out, points)
+	if !strings.HasPrefix(out.String(), "time,period,statistic,field,value\n2024-05-01T01:00:00Z,hour,failed,current_percent,2.5\n") {
+		t.Log(out.String())
+		t.Fail()
+	}
+
+	series := Group(points)
+	if len(series) != 2 || series[1].Name != "hour.failed.current_percent" || series[1].Points[0].Value != 1 {
+		t.Log(series)
+		t.Fail()
+	}
+
+	out.Reset()
+	WriteJSON(&out, points)
+	if !strings.Contains(out.String(), `"name": "date.delivered.current_value"`) {
+		t.Log(out.String())
+		t.Fail()
+	}
+
+	out.Reset()
+	WriteOpenMetrics(&out, points)
+	expected := "# TYPE postageapp_current_percent gauge\n" +
+		"# HELP postageapp_current_percent Share of messages in the current period.\n" +
+		"postageapp_current_percent{period=\"hour\",statistic=\"failed\"} 1 1714521600\n" +
+		"postageapp_current_percent{period=\"hour\",statistic=\"failed\"} 2.5 1714525200\n" +
+		"# TYPE postageapp_current_value gauge\n" +
+		"# HELP postageapp_current_value Number of messages in the current period.\n" +
+		"postageapp_current_value{period=\"date\",statistic=\"delivered\"} 40 1714521600\n" +
+		"# EOF\n"
+	if out.String() != expected {
+		t.Log(out.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+var Fields = []string{"current_percent", "previous_percent", "diff_percent", "current_value", "previous_value"}
+
+type Point struct {
+	Time      time.Time `json:"time"`
+	Period    string    `json:"period"`
+	Statistic string    `json:"statistic"`
+	Field     string    `json:"field"`
+	Value     float64   `json:"value"`
+}
+
+func (point Point) Series() string {
+	return point.Period + "." + point.Statistic + "." + point.Field
+}
+
+type Fetcher interface {
+	GetMetricsContext(ctx context.Context) (*postage_app.MetricsResponse, error)
+}
+
+type Poller struct {
+	Client   Fetcher
+	Store    Store
+	Interval time.Duration
+	OnError  func(error)
+
+	now func() time.Time
+}
+
+func NewPoller(client Fetcher, store Store, interval time.Duration) *Poller {
+	poller := new(Poller)
+	poller.Client = client
+	poller.Store = store
+	poller.Interval = interval
+	return poller
+}
+
+func (poller *Poller) Poll(ctx context.Context) ([]Point, error) {
+	response, err := poller.Client.GetMetricsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now
+	if poller.now != nil {
+		now = poller.now
+	}
+	points := Flatten(response.Data, now().UTC())
+	if err := poller.Store.Append(points); err != nil {
+		return nil, err
+	}
+	return points, nil
+}
+
+func (poller *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(poller.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := poller.Poll(ctx); err != nil && poller.OnError != nil && ctx.Err() == nil {
+			poller.OnError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func Flatten(metrics *postage_app.Metrics, at time.Time) []Point {
+	var points []Point
+	if metrics == nil {
+		return points
+	}
+	metrics.Each(func(period string, metric *postage_app.Metric) {
+		metric.Each(func(name string, statistic *postage_app.MetricStatistic) {
+			for _, field := range Fields {
+				if value, ok := statistic.Field(field); ok {
+					points = append(points, Point{at, period, name, field, value})
+				}
+			}
+		})
+	})
+	return points
+}
@@ -0,0 +1,194 @@
+package metrics
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Store interface {
+	Append(points []Point) error
+	Points(from time.Time, to time.Time) ([]Point, error)
+}
+
+func inRange(point Point, from time.Time, to time.Time) bool {
+	return (from.IsZero() || !point.Time.Before(from)) && (to.IsZero() || point.Time.Before(to))
+}
+
+type MemoryStore struct {
+	Capacity int
+
+	mu     sync.Mutex
+	points []Point
+	start  int
+}
+
+func NewMemoryStore(capacity int) *MemoryStore {
+	store := new(MemoryStore)
+	store.Capacity = capacity
+	return store
+}
+
+func (store *MemoryStore) Append(points []Point) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	for _, point := range points {
+		if store.Capacity <= 0 || len(store.points) < store.Capacity {
+			store.points = append(store.points, point)
+			continue
+		}
+		store.points[store.start] = point
+		store.start = (store.start + 1) % len(store.points)
+	}
+	return nil
+}
+
+func (store *MemoryStore) Points(from time.Time, to time.Time) ([]Point, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	var points []Point
+	for i := range store.points {
+		point := store.points[(store.start+i)%len(store.points)]
+		if inRange(point, from, to) {
+			points = append(points, point)
+		}
+	}
+	return points, nil
+}
+
+func (store *MemoryStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.points)
+}
+
+const (
+	FormatCSV       = "csv"
+	FormatJSONLines = "jsonl"
+)
+
+type FileStore struct {
+	Path   string
+	Format string
+
+	mu sync.Mutex
+}
+
+func NewFileStore(path string, format string) *FileStore {
+	store := new(FileStore)
+	store.Path = path
+	store.Format = format
+	return store
+}
+
+func NewCSVStore(path string) *FileStore {
+	return NewFileStore(path, FormatCSV)
+}
+
+func NewJSONLinesStore(path string) *FileStore {
+	return NewFileStore(path, FormatJSONLines)
+}
+
+func (store *FileStore) Append(points []Point) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	file, err := os.OpenFile(store.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if store.Format == FormatJSONLines {
+		encoder := json.NewEncoder(file)
+		for _, point := range points {
+			if err := encoder.Encode(point); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	writer := csv.NewWriter(file)
+	for _, point := range points {
+		writer.Write(csvRecord(point))
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (store *FileStore) Points(from time.Time, to time.Time) ([]Point, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	file, err := os.Open(store.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var points []Point
+	if store.Format == FormatJSONLines {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			var point Point
+			if err := json.Unmarshal(scanner.Bytes(), &point); err != nil {
+				return nil, err
+			}
+			if inRange(point, from, to) {
+				points = append(points, point)
+			}
+		}
+		return points, scanner.Err()
+	}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(csvHeader)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return points, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		point, err := parseCSVRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		if inRange(point, from, to) {
+			points = append(points, point)
+		}
+	}
+}
+
+var csvHeader = []string{"time", "period", "statistic", "field", "value"}
+
+func csvRecord(point Point) []string {
+	return []string{point.Time.UTC().Format(time.RFC3339Nano), point.Period, point.Statistic, point.Field,
+		strconv.FormatFloat(point.Value, 'g', -1, 64)}
+}
+
+func parseCSVRecord(record []string) (Point, error) {
+	at, err := time.Parse(time.RFC3339Nano, record[0])
+	if err != nil {
+		return Point{}, err
+	}
+	value, err := strconv.ParseFloat(record[4], 64)
+	if err != nil {
+		return Point{}, err
+	}
+	return Point{at, record[1], record[2], record[3], value}, nil
+}
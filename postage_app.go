@@ -2,11 +2,16 @@ package postage_app
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,6 +22,23 @@ const (
 type Client struct {
 	ApiKey  string
 	BaseUrl string
+
+	httpClient      *http.Client
+	timeout         time.Duration
+	retry           *RetryPolicy
+	rateLimiter     *RateLimiter
+	logger          *slog.Logger
+	levels          *LogLevels
+	logPayloads     bool
+	userAgent       string
+	keyRotation     *KeyRotation
+	sandbox         *Sandbox
+	recipientPolicy *RecipientPolicy
+	circuitBreaker  *CircuitBreaker
+	outbox          *Outbox
+	suppressions    *SuppressionList
+
+	middleware atomic.Value
 }
 
 type Attachment struct {
@@ -43,9 +65,12 @@ type Message struct {
 	ReplyTo           string
 	Text              string
 	Html              string
+	Project           string
 }
 
 type MessageInfo struct {
+	Uid                         string
+	Id                          int
 	ProjectId                   int
 	Template                    string
 	TotalTransmissionsCount     int
@@ -61,12 +86,13 @@ type MessageReceipt struct {
 }
 
 type MessageTransmission struct {
-	Status        string
+	Status        TransmissionStatus
 	ResultCode    string
 	ResultMessage string
 	CreatedAt     time.Time
-	FailedAt      time.Time
-	OpenedAt      time.Time
+	FailedAt      NullTime
+	OpenedAt      NullTime
+	ClickedAt     NullTime
 }
 
 type MessageTransmissions struct {
@@ -100,6 +126,15 @@ type MetricStatistic struct {
 	DiffPercent     float64
 	CurrentValue    int
 	PreviousValue   int
+	Valid           MetricValid
+}
+
+type MetricValid struct {
+	CurrentPercent  bool
+	PreviousPercent bool
+	DiffPercent     bool
+	CurrentValue    bool
+	PreviousValue   bool
 }
 
 type Metric struct {
@@ -111,6 +146,7 @@ type Metric struct {
 	Queued    *MetricStatistic
 	Clicked   *MetricStatistic
 	Spammed   *MetricStatistic
+	Extra     map[string]*MetricStatistic
 }
 
 type Metrics struct {
@@ -118,6 +154,7 @@ type Metrics struct {
 	Date  *Metric
 	Week  *Metric
 	Month *Metric
+	Extra map[string]*Metric
 }
 
 type Response struct {
@@ -147,8 +184,11 @@ type MetricsResponse struct {
 }
 
 type MessageResponse struct {
-	Response *Response
-	Data     *MessageReceipt
+	Response   *Response
+	Data       *MessageReceipt
+	Policy     *PolicyReport
+	Suppressed []*Suppression
+	Queued     bool
 }
 
 type MessageTransmissionsResponse struct {
@@ -177,45 +217,208 @@ func (e *PostageResponseError) Error() string {
 	return e.Message
 }
 
-func (client *Client) post(path string, params string) (map[string]interface{}, error) {
-	b := bytes.NewBufferString(params)
-	return client.postBuffer(path, b)
+func (e *PostageResponseError) Unwrap() error {
+	return e.InnerError
+}
+
+func newUid() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (client *Client) params(uid string) []byte {
+	b, _ := json.Marshal(map[string]string{"api_key": client.ApiKey, "uid": uid})
+	return b
+}
+
+func (client *Client) post(ctx context.Context, endpoint string, request interface{}, body []byte) (map[string]interface{}, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	call := &Call{Endpoint: endpoint, Request: request, Context: ctx, Body: body, Header: http.Header{}}
+	return client.invoke(call)
+}
+
+func (client *Client) dispatch(call *Call) (map[string]interface{}, error) {
+	if client.sandbox != nil {
+		if message, ok := call.Request.(*Message); ok && call.Endpoint == "send_message" {
+			return client.sandbox.sendMessage(message, call.Body)
+		}
+		return client.sandbox.respond(call.Endpoint+".json", call.Body)
+	}
+	return client.guard(call, client.deliver)
 }
 
-func (client *Client) postBuffer(path string, b *bytes.Buffer) (map[string]interface{}, error) {
-	if client.BaseUrl == "" {
-		client.BaseUrl = Url
+func (client *Client) deliver(call *Call) (map[string]interface{}, error) {
+	if client.rateLimiter != nil {
+		wait, err := client.rateLimiter.WaitContext(call.Context)
+		call.RateLimitWait = wait
+		if err != nil {
+			return nil, &PostageResponseError{err.Error(), err}
+		}
+	}
+	if client.keyRotation != nil {
+		return client.keyRotation.post(client, call)
 	}
-	url := Url + path
-	response, err := http.Post(url, "application/json", b)
+	return client.send(call, call.Body)
+}
+
+func (client *Client) send(call *Call, body []byte) (map[string]interface{}, error) {
+	m, err := client.sendAttempts(call, body)
+	return m, redactError(err, payloadApiKey(body), client.ApiKey)
+}
+
+func (client *Client) sendAttempts(call *Call, body []byte) (map[string]interface{}, error) {
+	baseUrl := client.BaseUrl
+	if baseUrl == "" {
+		baseUrl = Url
+	}
+	url := baseUrl + call.Endpoint + ".json"
+	if call.Context == nil {
+		call.Context = context.Background()
+	}
+
+	httpClient := client.httpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: client.timeout}
+	}
+
+	uid := payloadUid(body)
+	keys := []string{payloadApiKey(body), client.ApiKey}
+	client.logPayload("postageapp: request payload", call.Endpoint, body)
+
+	var err error
+	attempts := client.retry.attempts()
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			delay := client.retry.delay(attempt - 1)
+			if call.OnRetry != nil {
+				call.OnRetry(attempt, delay, err)
+			}
+			select {
+			case <-call.Context.Done():
+				return nil, &PostageResponseError{call.Context.Err().Error(), call.Context.Err()}
+			case <-time.After(delay):
+			}
+		}
+
+		logged := &callLog{endpoint: call.Endpoint, uid: uid, attempt: attempt, started: time.Now()}
+		call.Attempts = attempt
+		var retryable bool
+		retryable, err = client.attempt(httpClient, call, url, body)
+		if call.HTTPResponse != nil {
+			logged.httpStatus = call.HTTPResponse.StatusCode
+		}
+		if err == nil {
+			parseError := json.Unmarshal(call.ResponseBody, &logged.response)
+			if parseError != nil {
+				err = &PostageResponseError{parseError.Error(), parseError}
+			}
+		}
+		logged.err = err
+		client.logCall(logged, retryable && attempt < attempts, keys...)
+		if err == nil {
+			client.logPayload("postageapp: response payload", call.Endpoint, call.ResponseBody)
+			return logged.response, nil
+		}
+		if !retryable {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (client *Client) attempt(httpClient *http.Client, call *Call, url string, body []byte) (bool, error) {
+	call.HTTPRequest, call.HTTPResponse, call.ResponseBody = nil, nil, nil
+
+	ctx := call.Context
+	request, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return nil, &PostageResponseError{err.Error(), err}
+		return false, &PostageResponseError{err.Error(), err}
+	}
+	request.Header.Set("Content-Type", "application/json")
+	userAgent := client.userAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
 	}
+	request.Header.Set("User-Agent", userAgent)
+	for name, values := range call.Header {
+		request.Header[name] = values
+	}
+	call.HTTPRequest = request
 
-	bs, err := ioutil.ReadAll(response.Body)
+	response, err := httpClient.Do(request)
 	if err != nil {
-		log.Fatal(err)
+		return ctx.Err() == nil, &PostageResponseError{err.Error(), err}
 	}
 
-	var f interface{}
-	parseError := json.Unmarshal(bs, &f)
-	if parseError != nil {
-		return nil, &PostageResponseError{err.Error(), err}
+	bs, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(bs))
+	call.HTTPResponse = response
+	if err != nil {
+		return true, &PostageResponseError{err.Error(), err}
 	}
+	call.ResponseBody = bs
 
-	return f.(map[string]interface{}), nil
+	if response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests {
+		return true, &PostageResponseError{fmt.Sprintf("unexpected HTTP status %d", response.StatusCode), nil}
+	}
+	return false, nil
 }
 
 func (client *Client) SendMessage(message *Message) (*MessageResponse, error) {
-	bts, _ := client.MarshalMessage(message)
-	b := bytes.NewBuffer(bts)
+	return client.SendMessageContext(context.Background(), message)
+}
+
+func (client *Client) SendMessageContext(ctx context.Context, message *Message) (*MessageResponse, error) {
+	response, err := client.sendMessage(ctx, message)
+	if err != nil && client.outbox != nil && errors.Is(err, ErrCircuitOpen) {
+		return client.outbox.enqueue(message)
+	}
+	return response, err
+}
+
+func (client *Client) sendMessage(ctx context.Context, message *Message) (*MessageResponse, error) {
+	var report *PolicyReport
+	if client.recipientPolicy != nil {
+		var err error
+		message, report, err = client.recipientPolicy.Apply(message)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var suppressed []*Suppression
+	if client.suppressions != nil {
+		var err error
+		message, suppressed, err = client.suppressions.Apply(message)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if message.Uid == "" && client.retry.attempts() > 1 {
+		withUid := *message
+		withUid.Uid = newUid()
+		message = &withUid
+	}
 
-	m, err := client.postBuffer("send_message.json", b)
+	bts, err := client.MarshalMessage(message)
+	if err != nil {
+		return nil, &PostageError{err.Error(), err}
+	}
+
+	m, err := client.post(ctx, "send_message", message, bts)
 	if err != nil {
 		return nil, err
 	}
 	messageResponse := new(MessageResponse)
 	messageResponse.Response = client.ParseResponse(m["response"].(map[string]interface{}))
+	messageResponse.Policy = report
+	messageResponse.Suppressed = suppressed
 
 	if messageResponse.Response.Status == "ok" {
 		data := m["data"].(map[string]interface{})
@@ -232,7 +435,11 @@ func (client *Client) SendMessage(message *Message) (*MessageResponse, error) {
 }
 
 func (client *Client) GetMessages() (*MessagesResponse, error) {
-	m, err := client.post("get_messages.json", fmt.Sprintf(`{"api_key":"%s", "uid":"%s"}`, client.ApiKey))
+	return client.GetMessagesContext(context.Background())
+}
+
+func (client *Client) GetMessagesContext(ctx context.Context) (*MessagesResponse, error) {
+	m, err := client.post(ctx, "get_messages", nil, client.params(""))
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +449,10 @@ func (client *Client) GetMessages() (*MessagesResponse, error) {
 	messagesResponse.Response = client.ParseResponse(m["response"].(map[string]interface{}))
 
 	if messagesResponse.Response.Status == "ok" {
-		messagesResponse.Data = client.ParseMessages(m["data"].(map[string]interface{}))
+		messagesResponse.Data, err = client.ParseMessages(m["data"].(map[string]interface{}))
+		if err != nil {
+			return nil, &ResponseParseError{err.Error(), err}
+		}
 	} else {
 		return nil, &ResponseParseError{messagesResponse.Response.Status, nil}
 	}
@@ -251,7 +461,11 @@ func (client *Client) GetMessages() (*MessagesResponse, error) {
 }
 
 func (client *Client) GetProjectInfo() (*ProjectResponse, error) {
-	m, err := client.post("get_project_info.json", fmt.Sprintf(`{"api_key":"%s", "uid":"%s"}`, client.ApiKey))
+	return client.GetProjectInfoContext(context.Background())
+}
+
+func (client *Client) GetProjectInfoContext(ctx context.Context) (*ProjectResponse, error) {
+	m, err := client.post(ctx, "get_project_info", nil, client.params(""))
 	if err != nil {
 		return nil, err
 	}
@@ -270,7 +484,11 @@ func (client *Client) GetProjectInfo() (*ProjectResponse, error) {
 }
 
 func (client *Client) GetAccountInfo() (*AccountResponse, error) {
-	m, err := client.post("get_account_info.json", fmt.Sprintf(`{"api_key":"%s", "uid":"%s"}`, client.ApiKey))
+	return client.GetAccountInfoContext(context.Background())
+}
+
+func (client *Client) GetAccountInfoContext(ctx context.Context) (*AccountResponse, error) {
+	m, err := client.post(ctx, "get_account_info", nil, client.params(""))
 	if err != nil {
 		return nil, err
 	}
@@ -288,7 +506,11 @@ func (client *Client) GetAccountInfo() (*AccountResponse, error) {
 }
 
 func (client *Client) GetMetrics() (*MetricsResponse, error) {
-	m, err := client.post("get_metrics.json", fmt.Sprintf(`{"api_key":"%s", "uid":"%s"}`, client.ApiKey))
+	return client.GetMetricsContext(context.Background())
+}
+
+func (client *Client) GetMetricsContext(ctx context.Context) (*MetricsResponse, error) {
+	m, err := client.post(ctx, "get_metrics", nil, client.params(""))
 	if err != nil {
 		return nil, err
 	}
@@ -307,7 +529,11 @@ func (client *Client) GetMetrics() (*MetricsResponse, error) {
 }
 
 func (client *Client) GetMessageReceipt(uid string) (*MessageResponse, error) {
-	m, err := client.post("get_message_receipt.json", fmt.Sprintf(`{"api_key":"%s", "uid":"%s"}`, client.ApiKey, uid))
+	return client.GetMessageReceiptContext(context.Background(), uid)
+}
+
+func (client *Client) GetMessageReceiptContext(ctx context.Context, uid string) (*MessageResponse, error) {
+	m, err := client.post(ctx, "get_message_receipt", uid, client.params(uid))
 	if err != nil {
 		return nil, err
 	}
@@ -324,7 +550,11 @@ func (client *Client) GetMessageReceipt(uid string) (*MessageResponse, error) {
 }
 
 func (client *Client) GetMessageTransmissions(uid string) (*MessageTransmissionsResponse, error) {
-	m, err := client.post("get_message_transmissions.json", fmt.Sprintf(`{"api_key":"%s", "uid":"%s"}`, client.ApiKey, uid))
+	return client.GetMessageTransmissionsContext(context.Background(), uid)
+}
+
+func (client *Client) GetMessageTransmissionsContext(ctx context.Context, uid string) (*MessageTransmissionsResponse, error) {
+	m, err := client.post(ctx, "get_message_transmissions", uid, client.params(uid))
 	if err != nil {
 		return nil, err
 	}
@@ -333,7 +563,10 @@ func (client *Client) GetMessageTransmissions(uid string) (*MessageTransmissions
 	messageTransmissionsResponse.Response = client.ParseResponse(m["response"].(map[string]interface{}))
 
 	if messageTransmissionsResponse.Response.Status == "ok" {
-		messageTransmissionsResponse.Data = client.ParseMessageTransmissions(m["data"].(map[string]interface{}))
+		messageTransmissionsResponse.Data, err = client.ParseMessageTransmissions(m["data"].(map[string]interface{}))
+		if err != nil {
+			return nil, &ResponseParseError{err.Error(), err}
+		}
 
 	} else {
 		return nil, &ResponseParseError{messageTransmissionsResponse.Response.Status, nil}
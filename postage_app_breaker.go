@@ -0,0 +1,222 @@
+package postage_app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type BreakerState int
+
+const (
+	BreakerClosed BreakerState = iota
+	BreakerOpen
+	BreakerHalfOpen
+)
+
+func (state BreakerState) String() string {
+	switch state {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("BreakerState(%d)", int(state))
+}
+
+type CircuitOpenError struct {
+	RetryAfter time.Duration
+}
+
+var ErrCircuitOpen = &CircuitOpenError{}
+
+func (e *CircuitOpenError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("circuit breaker is open, retry after %v", e.RetryAfter)
+	}
+	return "circuit breaker is open"
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	_, ok := target.(*CircuitOpenError)
+	return ok
+}
+
+type BreakerStats struct {
+	State               BreakerState
+	Requests            int
+	Failures            int
+	ConsecutiveFailures int
+	OpenedAt            time.Time
+}
+
+type CircuitBreaker struct {
+	ConsecutiveFailures int
+	FailureRatio        float64
+	MinRequests         int
+	Window              time.Duration
+	OpenTimeout         time.Duration
+	HalfOpenProbes      int
+
+	mu          sync.Mutex
+	now         func() time.Time
+	state       BreakerState
+	openedAt    time.Time
+	windowStart time.Time
+	requests    int
+	failures    int
+	consecutive int
+	probes      int
+	successes   int
+}
+
+func NewCircuitBreaker(consecutiveFailures int, failureRatio float64, openTimeout time.Duration) *CircuitBreaker {
+	breaker := new(CircuitBreaker)
+	breaker.ConsecutiveFailures = consecutiveFailures
+	breaker.FailureRatio = failureRatio
+	breaker.MinRequests = 10
+	breaker.Window = time.Minute
+	breaker.OpenTimeout = openTimeout
+	breaker.HalfOpenProbes = 1
+	return breaker
+}
+
+func (breaker *CircuitBreaker) clock() time.Time {
+	if breaker.now != nil {
+		return breaker.now()
+	}
+	return time.Now()
+}
+
+func (breaker *CircuitBreaker) State() BreakerState {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.advance(breaker.clock())
+	return breaker.state
+}
+
+func (breaker *CircuitBreaker) Stats() BreakerStats {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.advance(breaker.clock())
+	return BreakerStats{breaker.state, breaker.requests, breaker.failures, breaker.consecutive, breaker.openedAt}
+}
+
+func (breaker *CircuitBreaker) Healthy() bool {
+	return breaker.State() != BreakerOpen
+}
+
+func (breaker *CircuitBreaker) Reset() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	breaker.transition(BreakerClosed, breaker.clock())
+}
+
+func (breaker *CircuitBreaker) advance(now time.Time) {
+	if breaker.state == BreakerOpen && now.Sub(breaker.openedAt) >= breaker.OpenTimeout {
+		breaker.transition(BreakerHalfOpen, now)
+	}
+	if breaker.state == BreakerClosed && breaker.Window > 0 && now.Sub(breaker.windowStart) >= breaker.Window {
+		breaker.windowStart = now
+		breaker.requests = 0
+		breaker.failures = 0
+	}
+}
+
+func (breaker *CircuitBreaker) transition(state BreakerState, now time.Time) {
+	breaker.state = state
+	breaker.windowStart = now
+	breaker.requests = 0
+	breaker.failures = 0
+	breaker.consecutive = 0
+	breaker.probes = 0
+	breaker.successes = 0
+	if state == BreakerOpen {
+		breaker.openedAt = now
+	}
+}
+
+func (breaker *CircuitBreaker) allow() error {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	now := breaker.clock()
+	breaker.advance(now)
+
+	switch breaker.state {
+	case BreakerOpen:
+		return &CircuitOpenError{breaker.OpenTimeout - now.Sub(breaker.openedAt)}
+	case BreakerHalfOpen:
+		if breaker.probes >= max(breaker.HalfOpenProbes, 1) {
+			return &CircuitOpenError{}
+		}
+		breaker.probes++
+	}
+	return nil
+}
+
+func (breaker *CircuitBreaker) release() {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	if breaker.state == BreakerHalfOpen && breaker.probes > 0 {
+		breaker.probes--
+	}
+}
+
+func (breaker *CircuitBreaker) record(failed bool) {
+	breaker.mu.Lock()
+	defer breaker.mu.Unlock()
+	now := breaker.clock()
+	breaker.advance(now)
+
+	if breaker.state == BreakerHalfOpen {
+		if failed {
+			breaker.transition(BreakerOpen, now)
+			return
+		}
+		breaker.successes++
+		if breaker.successes >= max(breaker.HalfOpenProbes, 1) {
+			breaker.transition(BreakerClosed, now)
+		}
+		return
+	}
+	if breaker.state != BreakerClosed {
+		return
+	}
+
+	breaker.requests++
+	if !failed {
+		breaker.consecutive = 0
+		return
+	}
+	breaker.failures++
+	breaker.consecutive++
+
+	if breaker.ConsecutiveFailures > 0 && breaker.consecutive >= breaker.ConsecutiveFailures {
+		breaker.transition(BreakerOpen, now)
+		return
+	}
+	if breaker.FailureRatio > 0 && breaker.requests >= breaker.MinRequests &&
+		float64(breaker.failures)/float64(breaker.requests) >= breaker.FailureRatio {
+		breaker.transition(BreakerOpen, now)
+	}
+}
+
+func (client *Client) guard(call *Call, deliver func(call *Call) (map[string]interface{}, error)) (map[string]interface{}, error) {
+	if client.circuitBreaker == nil {
+		return deliver(call)
+	}
+	if err := client.circuitBreaker.allow(); err != nil {
+		return nil, err
+	}
+	m, err := deliver(call)
+	if errors.Is(call.Context.Err(), context.Canceled) {
+		client.circuitBreaker.release()
+	} else {
+		client.circuitBreaker.record(err != nil)
+	}
+	return m, err
+}
@@ -0,0 +1,123 @@
+package postage_app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func InitBreaker(t *testing.T, failing *atomic.Bool) (*Client, *CircuitBreaker, *time.Time, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	breaker := NewCircuitBreaker(3, 0, time.Minute)
+	breaker.now = func() time.Time { return now }
+	cl, _ := NewClient(ApiKey, WithBaseURL(server.URL), WithCircuitBreaker(breaker))
+	return cl, breaker, &now, &requests
+}
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	cl, breaker, now, requests := InitBreaker(t, &failing)
+
+	for i := 0; i < 3; i++ {
+		if _, err := cl.GetMessageReceipt("abc"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Log(i, err)
+			t.Fail()
+		}
+	}
+	if breaker.State() != BreakerOpen || breaker.Healthy() {
+		t.Log(breaker.Stats())
+		t.Fail()
+	}
+
+	_, err := cl.GetMessageReceipt("abc")
+	var open *CircuitOpenError
+	if !errors.Is(err, ErrCircuitOpen) || !errors.As(err, &open) || open.RetryAfter != time.Minute || requests.Load() != 3 {
+		t.Log(err, requests.Load())
+		t.Fail()
+	}
+
+	*now = now.Add(time.Minute)
+	if breaker.State() != BreakerHalfOpen {
+		t.Log(breaker.State())
+		t.Fail()
+	}
+	if _, err := cl.GetMessageReceipt("abc"); err == nil || breaker.State() != BreakerOpen {
+		t.Log("failed probe should reopen", err, breaker.State())
+		t.Fail()
+	}
+
+	failing.Store(false)
+	*now = now.Add(time.Minute)
+	if _, err := cl.GetMessageReceipt("abc"); err != nil || breaker.State() != BreakerClosed {
+		t.Log("successful probe should close", err, breaker.State())
+		t.Fail()
+	}
+}
+
+func TestBreakerFailureRatio(t *testing.T) {
+	breaker := NewCircuitBreaker(0, 0.5, time.Minute)
+	breaker.MinRequests = 4
+	for _, failed := range []bool{true, false, true} {
+		breaker.record(failed)
+	}
+	if breaker.State() != BreakerClosed {
+		t.Fail()
+	}
+	breaker.record(true)
+	if breaker.State() != BreakerOpen {
+		t.Log(breaker.Stats())
+		t.Fail()
+	}
+}
+
+func TestBreakerQueuesToOutbox(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	cl, breaker, now, requests := InitBreaker(t, &failing)
+	cl.outbox = NewOutbox(0)
+
+	_, _, message := InitSandboxMessage()
+	message.Uid = ""
+	for i := 0; i < 3; i++ {
+		cl.SendMessage(message)
+	}
+
+	response, err := cl.SendMessage(message)
+	if err != nil || !response.Queued || response.Response.Status != "queued" || response.Response.Uid == "" || cl.outbox.Len() != 1 {
+		t.Log(response, err)
+		t.Fatal(cl.outbox.Len())
+	}
+
+	if sent, err := cl.outbox.Flush(context.Background(), cl); sent != 0 || !errors.Is(err, ErrCircuitOpen) || cl.outbox.Len() != 1 {
+		t.Log(sent, err)
+		t.Fail()
+	}
+
+	failing.Store(false)
+	*now = now.Add(time.Minute)
+	before := requests.Load()
+	if sent, err := cl.outbox.Flush(context.Background(), cl); sent != 1 || err != nil || cl.outbox.Len() != 0 {
+		t.Log(sent, err)
+		t.Fail()
+	}
+	if requests.Load() != before+1 || breaker.State() != BreakerClosed {
+		t.Log(requests.Load(), breaker.State())
+		t.Fail()
+	}
+}
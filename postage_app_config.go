@@ -0,0 +1,432 @@
+package postage_app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ConfigEnv = "POSTAGEAPP_CONFIG"
+
+type Config struct {
+	ApiKey               string
+	BaseUrl              string
+	Timeout              time.Duration
+	RetryMaxAttempts     int
+	RetryBackoff         time.Duration
+	RetryMaxBackoff      time.Duration
+	RateLimit            float64
+	RateBurst            int
+	RecipientOverride    string
+	RecipientAllow       []string
+	RecipientBlockOthers bool
+
+	sources map[string]string
+}
+
+type ConfigError struct {
+	Setting string
+	Source  string
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Source == "" {
+		return fmt.Sprintf("config: %s: %s", e.Setting, e.Message)
+	}
+	return fmt.Sprintf("config: %s (%s): %s", e.Setting, e.Source, e.Message)
+}
+
+var configSettings = []struct {
+	key string
+	set func(config *Config, value string) error
+}{
+	{"api_key", func(config *Config, value string) error {
+		config.ApiKey = value
+		return nil
+	}},
+	{"base_url", func(config *Config, value string) error {
+		config.BaseUrl = value
+		return nil
+	}},
+	{"timeout", func(config *Config, value string) (err error) {
+		config.Timeout, err = parseConfigDuration(value)
+		return
+	}},
+	{"retry_max_attempts", func(config *Config, value string) (err error) {
+		config.RetryMaxAttempts, err = parseConfigInt(value)
+		return
+	}},
+	{"retry_backoff", func(config *Config, value string) (err error) {
+		config.RetryBackoff, err = parseConfigDuration(value)
+		return
+	}},
+	{"retry_max_backoff", func(config *Config, value string) (err error) {
+		config.RetryMaxBackoff, err = parseConfigDuration(value)
+		return
+	}},
+	{"rate_limit", func(config *Config, value string) error {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", value)
+		}
+		config.RateLimit = rate
+		return nil
+	}},
+	{"rate_burst", func(config *Config, value string) (err error) {
+		config.RateBurst, err = parseConfigInt(value)
+		return
+	}},
+	{"recipient_override", func(config *Config, value string) error {
+		config.RecipientOverride = value
+		return nil
+	}},
+	{"recipient_allow", func(config *Config, value string) error {
+		config.RecipientAllow = nil
+		for _, entry := range strings.Split(value, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				config.RecipientAllow = append(config.RecipientAllow, entry)
+			}
+		}
+		return nil
+	}},
+	{"recipient_block_others", func(config *Config, value string) error {
+		block, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		config.RecipientBlockOthers = block
+		return nil
+	}},
+}
+
+func configEnvName(key string) string {
+	return "POSTAGEAPP_" + strings.ToUpper(key)
+}
+
+func parseConfigDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return duration, nil
+}
+
+func parseConfigInt(value string) (int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	return i, nil
+}
+
+func (config *Config) set(key string, value string, source string) error {
+	key = strings.ToLower(strings.TrimSpace(key))
+	for _, setting := range configSettings {
+		if setting.key != key {
+			continue
+		}
+		if err := setting.set(config, strings.TrimSpace(value)); err != nil {
+			if key == "api_key" {
+				return &ConfigError{key, source, "invalid value"}
+			}
+			return &ConfigError{key, source, err.Error()}
+		}
+		if config.sources == nil {
+			config.sources = make(map[string]string)
+		}
+		config.sources[key] = source
+		return nil
+	}
+	return &ConfigError{key, source, "unknown setting"}
+}
+
+func (config *Config) source(key string) string {
+	if source, ok := config.sources[key]; ok {
+		return source
+	}
+	return configEnvName(key)
+}
+
+func ConfigFromEnv() (*Config, error) {
+	if path := os.Getenv(ConfigEnv); path != "" {
+		return LoadConfig(path)
+	}
+	config := new(Config)
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+	return config, config.Validate()
+}
+
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ConfigError{"file", path, err.Error()}
+	}
+
+	config := new(Config)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = config.loadJson(content, path)
+	case ".yaml", ".yml":
+		err = config.loadFlat(content, path, ":")
+	case ".toml":
+		err = config.loadFlat(content, path, "=")
+	default:
+		err = &ConfigError{"file", path, "unsupported config format, use .json, .yaml or .toml"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+	return config, config.Validate()
+}
+
+func NewClientFromEnv() (*Client, error) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return config.NewClient()
+}
+
+func (config *Config) loadEnv() error {
+	for _, setting := range configSettings {
+		name := configEnvName(setting.key)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := config.set(setting.key, value, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (config *Config) loadJson(content []byte, path string) error {
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return &ConfigError{"file", path, err.Error()}
+	}
+	for key, value := range values {
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case json.Number:
+			s = v.String()
+		case bool:
+			s = strconv.FormatBool(v)
+		case []interface{}:
+			entries := make([]string, len(v))
+			for i, entry := range v {
+				entries[i] = fmt.Sprint(entry)
+			}
+			s = strings.Join(entries, ",")
+		case nil:
+			continue
+		default:
+			return &ConfigError{key, path, "unsupported value"}
+		}
+		if err := config.set(key, s, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (config *Config) loadFlat(content []byte, path string, separator string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	lineNumber := 0
+	listKey, listSource := "", ""
+	var list []string
+
+	flush := func() error {
+		if listKey == "" {
+			return nil
+		}
+		err := config.set(listKey, strings.Join(list, ","), listSource)
+		listKey, list = "", nil
+		return err
+	}
+
+	for scanner.Scan() {
+		lineNumber++
+		source := fmt.Sprintf("%s:%d", path, lineNumber)
+		line := strings.TrimSpace(stripConfigComment(scanner.Text()))
+		if line == "" || line == "---" || (separator == "=" && strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")) {
+			continue
+		}
+
+		if listKey != "" && strings.HasPrefix(line, "- ") {
+			list = append(list, unquoteConfigValue(strings.TrimSpace(line[2:])))
+			continue
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		i := strings.Index(line, separator)
+		if i < 0 {
+			return &ConfigError{"file", source, fmt.Sprintf("expected key %s value", separator)}
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+
+		if value == "" && separator == ":" {
+			listKey, listSource = key, source
+			continue
+		}
+		if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+			var entries []string
+			for _, entry := range strings.Split(value[1:len(value)-1], ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, unquoteConfigValue(entry))
+				}
+			}
+			value = strings.Join(entries, ",")
+		} else {
+			value = unquoteConfigValue(value)
+		}
+		if err := config.set(key, value, source); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return &ConfigError{"file", path, err.Error()}
+	}
+	return flush()
+}
+
+func stripConfigComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func unquoteConfigValue(value string) string {
+	if len(value) >= 2 {
+		if value[0] == '"' && value[len(value)-1] == '"' {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				return unquoted
+			}
+		}
+		if value[0] == '\'' && value[len(value)-1] == '\'' {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
+
+func (config *Config) Validate() error {
+	if config.ApiKey == "" {
+		return &ConfigError{"api_key", config.source("api_key"), "is required"}
+	}
+	if config.BaseUrl != "" {
+		u, err := url.Parse(config.BaseUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &ConfigError{"base_url", config.source("base_url"), fmt.Sprintf("invalid URL %q", config.BaseUrl)}
+		}
+	}
+	if config.Timeout < 0 {
+		return &ConfigError{"timeout", config.source("timeout"), "must not be negative"}
+	}
+	if config.RetryMaxAttempts < 0 {
+		return &ConfigError{"retry_max_attempts", config.source("retry_max_attempts"), "must not be negative"}
+	}
+	if config.RetryBackoff < 0 {
+		return &ConfigError{"retry_backoff", config.source("retry_backoff"), "must not be negative"}
+	}
+	if config.RetryMaxBackoff < 0 {
+		return &ConfigError{"retry_max_backoff", config.source("retry_max_backoff"), "must not be negative"}
+	}
+	if config.RateLimit < 0 {
+		return &ConfigError{"rate_limit", config.source("rate_limit"), "must not be negative"}
+	}
+	if config.RateBurst < 0 {
+		return &ConfigError{"rate_burst", config.source("rate_burst"), "must not be negative"}
+	}
+	if config.RecipientOverride != "" {
+		if _, err := mail.ParseAddress(config.RecipientOverride); err != nil {
+			return &ConfigError{"recipient_override", config.source("recipient_override"), fmt.Sprintf("invalid address %q", config.RecipientOverride)}
+		}
+	}
+	return nil
+}
+
+func (config *Config) NewClient(opts ...Option) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return NewClient(config.ApiKey, append(config.Options(), opts...)...)
+}
+
+func (config *Config) Options() []Option {
+	var opts []Option
+	if config.BaseUrl != "" {
+		opts = append(opts, WithBaseURL(config.BaseUrl))
+	}
+	if config.Timeout > 0 {
+		opts = append(opts, WithTimeout(config.Timeout))
+	}
+	if config.RetryMaxAttempts > 1 {
+		opts = append(opts, WithRetry(config.RetryMaxAttempts, config.RetryBackoff, config.RetryMaxBackoff))
+	}
+	if config.RateLimit > 0 {
+		opts = append(opts, WithRateLimit(config.RateLimit, config.RateBurst))
+	}
+	if config.RecipientOverride != "" || len(config.RecipientAllow) != 0 || config.RecipientBlockOthers {
+		policy := new(RecipientPolicy)
+		policy.Override = config.RecipientOverride
+		policy.Allow = config.RecipientAllow
+		policy.BlockOthers = config.RecipientBlockOthers
+		opts = append(opts, WithRecipientPolicy(policy))
+	}
+	return opts
+}
+
+func maskApiKey(apiKey string) string {
+	if apiKey == "" {
+		return ""
+	}
+	if len(apiKey) < 16 {
+		return "****"
+	}
+	return "****" + apiKey[len(apiKey)-4:]
+}
+
+func (config Config) String() string {
+	return fmt.Sprintf("Config{ApiKey:%q BaseUrl:%q Timeout:%v RetryMaxAttempts:%d RateLimit:%v RecipientOverride:%q}",
+		maskApiKey(config.ApiKey), config.BaseUrl, config.Timeout, config.RetryMaxAttempts, config.RateLimit, config.RecipientOverride)
+}
+
+func (config Config) GoString() string {
+	return config.String()
+}
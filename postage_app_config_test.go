@@ -0,0 +1,145 @@
+package postage_app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const configApiKey = "abc123ThisIsASecretKey"
+
+func writeConfig(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("POSTAGEAPP_API_KEY", configApiKey)
+	t.Setenv("POSTAGEAPP_BASE_URL", "https://api.example.com/v.1.0")
+	t.Setenv("POSTAGEAPP_TIMEOUT", "15s")
+	t.Setenv("POSTAGEAPP_RETRY_MAX_ATTEMPTS", "3")
+	t.Setenv("POSTAGEAPP_RATE_LIMIT", "5")
+	t.Setenv("POSTAGEAPP_RECIPIENT_OVERRIDE", "dev@acme.com")
+	t.Setenv("POSTAGEAPP_RECIPIENT_ALLOW", "acme.com, qa@example.com")
+
+	cl, err := NewClientFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.ApiKey != configApiKey || cl.BaseUrl != "https://api.example.com/v.1.0/" || cl.timeout != 15*time.Second {
+		t.Log(cl.BaseUrl, cl.timeout)
+		t.Fail()
+	}
+
+	if cl.retry == nil || cl.retry.MaxAttempts != 3 || cl.rateLimiter == nil {
+		t.Log(cl.retry, cl.rateLimiter)
+		t.Fail()
+	}
+
+	if cl.recipientPolicy == nil || cl.recipientPolicy.Override != "dev@acme.com" || len(cl.recipientPolicy.Allow) != 2 {
+		t.Log(cl.recipientPolicy)
+		t.Fail()
+	}
+}
+
+func TestLoadConfigFormats(t *testing.T) {
+	files := map[string]string{
+		"config.json": `{"api_key": "` + configApiKey + `", "timeout": 10, "retry_max_attempts": 2, "recipient_allow": ["acme.com", "example.com"], "recipient_block_others": true}`,
+		"config.yaml": "# PostageApp\napi_key: \"" + configApiKey + "\"\ntimeout: 10s # seconds\nretry_max_attempts: 2\nrecipient_allow:\n  - acme.com\n  - 'example.com'\nrecipient_block_others: true\n",
+		"config.toml": "[postageapp]\napi_key = \"" + configApiKey + "\"\ntimeout = \"10s\"\nretry_max_attempts = 2\nrecipient_allow = [\"acme.com\", \"example.com\"]\nrecipient_block_others = true\n",
+	}
+
+	for name, content := range files {
+		config, err := LoadConfig(writeConfig(t, name, content))
+		if err != nil {
+			t.Log(name, err)
+			t.Fail()
+			continue
+		}
+
+		if config.ApiKey != configApiKey || config.Timeout != 10*time.Second || config.RetryMaxAttempts != 2 {
+			t.Log(name, config)
+			t.Fail()
+		}
+
+		if strings.Join(config.RecipientAllow, ",") != "acme.com,example.com" || !config.RecipientBlockOthers {
+			t.Log(name, config.RecipientAllow, config.RecipientBlockOthers)
+			t.Fail()
+		}
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "api_key: "+configApiKey+"\ntimeout: 10s\n")
+	t.Setenv("POSTAGEAPP_TIMEOUT", "20s")
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Timeout != 20*time.Second {
+		t.Log(config.Timeout)
+		t.Fail()
+	}
+}
+
+func TestConfigErrorsPointAtSetting(t *testing.T) {
+	path := writeConfig(t, "config.yaml", "api_key: "+configApiKey+"\n\ntimeout: soon\n")
+	_, err := LoadConfig(path)
+
+	configError, ok := err.(*ConfigError)
+	if !ok {
+		t.Fatal(err)
+	}
+
+	if configError.Setting != "timeout" || configError.Source != path+":3" {
+		t.Log(configError)
+		t.Fail()
+	}
+
+	if strings.Contains(err.Error(), configApiKey) {
+		t.Log(err)
+		t.Fail()
+	}
+
+	t.Setenv("POSTAGEAPP_RATE_LIMIT", "-1")
+	t.Setenv("POSTAGEAPP_API_KEY", configApiKey)
+	_, err = ConfigFromEnv()
+	if err == nil || err.Error() != "config: rate_limit (POSTAGEAPP_RATE_LIMIT): must not be negative" {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestConfigRequiresApiKey(t *testing.T) {
+	t.Setenv("POSTAGEAPP_API_KEY", "")
+	_, err := NewClientFromEnv()
+	if err == nil || err.Error() != "config: api_key (POSTAGEAPP_API_KEY): is required" {
+		t.Log(err)
+		t.Fail()
+	}
+
+	_, err = LoadConfig(writeConfig(t, "config.yaml", "api_kee: "+configApiKey+"\n"))
+	if err == nil || strings.Contains(err.Error(), configApiKey) {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestConfigStringMasksApiKey(t *testing.T) {
+	config := &Config{ApiKey: configApiKey}
+	for _, s := range []string{config.String(), fmt.Sprintf("%v", config), fmt.Sprintf("%+v", *config), fmt.Sprintf("%#v", config)} {
+		if strings.Contains(s, configApiKey) {
+			t.Log(s)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package postage_app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type KeyProvider interface {
+	Keys() (primary string, secondary string, err error)
+}
+
+type StaticKeys struct {
+	Primary   string
+	Secondary string
+}
+
+func (keys *StaticKeys) Keys() (string, string, error) {
+	return keys.Primary, keys.Secondary, nil
+}
+
+type KeyFunc func() (string, string, error)
+
+func (f KeyFunc) Keys() (string, string, error) {
+	return f()
+}
+
+type FileKeys struct {
+	Path string
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	primary   string
+	secondary string
+}
+
+func NewFileKeys(path string) *FileKeys {
+	keys := new(FileKeys)
+	keys.Path = path
+	return keys
+}
+
+func (keys *FileKeys) Keys() (string, string, error) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	info, err := os.Stat(keys.Path)
+	if err != nil {
+		return "", "", &PostageError{"keys: " + err.Error(), err}
+	}
+	if info.ModTime().Equal(keys.modTime) && info.Size() == keys.size && keys.primary != "" {
+		return keys.primary, keys.secondary, nil
+	}
+
+	content, err := os.ReadFile(keys.Path)
+	if err != nil {
+		return "", "", &PostageError{"keys: " + err.Error(), err}
+	}
+	var found []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			found = append(found, line)
+		}
+	}
+	if len(found) == 0 {
+		return "", "", &PostageError{"keys: no api key in " + keys.Path, nil}
+	}
+
+	keys.primary = found[0]
+	keys.secondary = ""
+	if len(found) > 1 {
+		keys.secondary = found[1]
+	}
+	keys.modTime = info.ModTime()
+	keys.size = info.Size()
+	return keys.primary, keys.secondary, nil
+}
+
+type KeyStats struct {
+	PrimaryRequests   int64
+	SecondaryRequests int64
+	Fallbacks         int64
+	LastKey           string
+}
+
+type KeyRotation struct {
+	Provider KeyProvider
+
+	primaryRequests   atomic.Int64
+	secondaryRequests atomic.Int64
+	fallbacks         atomic.Int64
+	lastKey           atomic.Value
+	rejected          atomic.Value
+}
+
+func NewKeyRotation(provider KeyProvider) *KeyRotation {
+	rotation := new(KeyRotation)
+	rotation.Provider = provider
+	return rotation
+}
+
+func (rotation *KeyRotation) Stats() KeyStats {
+	stats := KeyStats{
+		PrimaryRequests:   rotation.primaryRequests.Load(),
+		SecondaryRequests: rotation.secondaryRequests.Load(),
+		Fallbacks:         rotation.fallbacks.Load(),
+	}
+	stats.LastKey, _ = rotation.lastKey.Load().(string)
+	return stats
+}
+
+func (client *Client) KeyStats() KeyStats {
+	if client.keyRotation == nil {
+		return KeyStats{}
+	}
+	return client.keyRotation.Stats()
+}
+
+func (rotation *KeyRotation) post(client *Client, call *Call) (map[string]interface{}, error) {
+	primary, secondary, err := rotation.Provider.Keys()
+	if err != nil {
+		return nil, err
+	}
+	if primary == "" {
+		return nil, &PostageError{"keys: provider returned no primary key", nil}
+	}
+	hasSecondary := secondary != "" && secondary != primary
+
+	if rejected, _ := rotation.rejected.Load().(string); rejected == primary && hasSecondary {
+		m, err := client.send(call, withApiKey(call.Body, secondary))
+		if err != nil || responseStatus(m) != "unauthorized" {
+			rotation.answered("secondary")
+			return m, err
+		}
+		hasSecondary = false
+	}
+
+	m, err := client.send(call, withApiKey(call.Body, primary))
+	if err != nil || responseStatus(m) != "unauthorized" || !hasSecondary {
+		if err == nil && responseStatus(m) != "unauthorized" {
+			rotation.rejected.CompareAndSwap(primary, "")
+		}
+		rotation.answered("primary")
+		return m, err
+	}
+
+	rotation.rejected.Store(primary)
+	rotation.fallbacks.Add(1)
+	if client.logger != nil {
+		client.logger.Warn("postageapp: primary api key was rejected, using secondary key", "endpoint", call.Endpoint)
+	}
+	m, err = client.send(call, withApiKey(call.Body, secondary))
+	rotation.answered("secondary")
+	return m, err
+}
+
+func (rotation *KeyRotation) answered(key string) {
+	if key == "primary" {
+		rotation.primaryRequests.Add(1)
+	} else {
+		rotation.secondaryRequests.Add(1)
+	}
+	rotation.lastKey.Store(key)
+}
+
+func withApiKey(body []byte, apiKey string) []byte {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return body
+	}
+	payload["api_key"] = apiKey
+	replaced, err := json.Marshal(payload)
+	if err != nil {
+		return body
+	}
+	return replaced
+}
+
+func responseStatus(m map[string]interface{}) string {
+	response, _ := m["response"].(map[string]interface{})
+	status, _ := response["status"].(string)
+	return status
+}
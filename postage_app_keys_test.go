@@ -0,0 +1,127 @@
+package postage_app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func keyServer(t *testing.T, valid string) (*httptest.Server, *[]string) {
+	var mu sync.Mutex
+	var seen []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		apiKey, _ := payload["api_key"].(string)
+		mu.Lock()
+		seen = append(seen, apiKey)
+		mu.Unlock()
+		if apiKey != valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"response":{"status":"unauthorized"}}`))
+			return
+		}
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &seen
+}
+
+func TestKeyRotationFallsBackToSecondary(t *testing.T) {
+	server, seen := keyServer(t, "new-key")
+	cl, err := NewClient("", WithBaseURL(server.URL), WithKeyProvider(&StaticKeys{"old-key", "new-key"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cl.GetMessageReceipt("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*seen) != 2 || (*seen)[0] != "old-key" || (*seen)[1] != "new-key" {
+		t.Log(*seen)
+		t.Fail()
+	}
+
+	stats := cl.KeyStats()
+	if stats.Fallbacks != 1 || stats.SecondaryRequests != 1 || stats.PrimaryRequests != 0 || stats.LastKey != "secondary" {
+		t.Log(stats)
+		t.Fail()
+	}
+}
+
+func TestKeyRotationRemembersRejectedPrimary(t *testing.T) {
+	server, seen := keyServer(t, "new-key")
+	keys := &StaticKeys{"old-key", "new-key"}
+	cl, _ := NewClient("", WithBaseURL(server.URL), WithKeyProvider(keys), WithRateLimit(0.001, 4))
+
+	for i := 0; i < 3; i++ {
+		if _, err := cl.GetMessageReceipt("abc"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*seen) != 4 || (*seen)[2] != "new-key" || (*seen)[3] != "new-key" {
+		t.Log(*seen)
+		t.Fail()
+	}
+	if stats := cl.KeyStats(); stats.Fallbacks != 1 || stats.SecondaryRequests != 3 {
+		t.Log(stats)
+		t.Fail()
+	}
+	if tokens := cl.rateLimiter.tokens; tokens < 0.5 {
+		t.Log("each call should use one rate limit token", tokens)
+		t.Fail()
+	}
+
+	keys.Primary, keys.Secondary = "new-key", "old-key"
+	*seen = nil
+	if _, err := cl.GetMessageReceipt("abc"); err != nil || len(*seen) != 1 || (*seen)[0] != "new-key" {
+		t.Log("a new primary should be tried first", *seen, err)
+		t.Fail()
+	}
+}
+
+func TestKeyRotationUnauthorizedWithoutSecondary(t *testing.T) {
+	server, _ := keyServer(t, "new-key")
+	cl, _ := NewClient("", WithBaseURL(server.URL), WithKeyProvider(KeyFunc(func() (string, string, error) {
+		return "old-key", "", nil
+	})))
+
+	_, err := cl.GetMessageReceipt("abc")
+	if err == nil || err.Error() != "unauthorized" {
+		t.Log(err)
+		t.Fail()
+	}
+
+	if cl.KeyStats().PrimaryRequests != 1 {
+		t.Log(cl.KeyStats())
+		t.Fail()
+	}
+}
+
+func TestFileKeysReloadOnChange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "postageapp.keys")
+	os.WriteFile(path, []byte("# rotated 2026-10-01\nold-key\n"), 0600)
+	keys := NewFileKeys(path)
+
+	primary, secondary, err := keys.Keys()
+	if err != nil || primary != "old-key" || secondary != "" {
+		t.Log(primary, secondary, err)
+		t.Fail()
+	}
+
+	os.WriteFile(path, []byte("new-key\nold-key\n"), 0600)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(path, future, future)
+
+	primary, secondary, err = keys.Keys()
+	if err != nil || primary != "new-key" || secondary != "old-key" {
+		t.Log(primary, secondary, err)
+		t.Fail()
+	}
+}
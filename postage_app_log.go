@@ -0,0 +1,144 @@
+package postage_app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+type LogLevels struct {
+	Call    slog.Level
+	Retry   slog.Level
+	Failure slog.Level
+}
+
+var DefaultLogLevels = LogLevels{slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+var emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-]+)@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+
+type callLog struct {
+	endpoint   string
+	uid        string
+	attempt    int
+	started    time.Time
+	httpStatus int
+	response   map[string]interface{}
+	err        error
+}
+
+func (client *Client) logLevels() LogLevels {
+	if client.levels == nil {
+		return DefaultLogLevels
+	}
+	return *client.levels
+}
+
+func (client *Client) logPayload(message string, endpoint string, payload []byte) {
+	if client.logger == nil || !client.logPayloads {
+		return
+	}
+	ctx := context.Background()
+	if !client.logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	client.logger.Log(ctx, slog.LevelDebug, message, "endpoint", endpoint, "payload", string(RedactPII(payload)))
+}
+
+func (client *Client) logCall(call *callLog, retrying bool, keys ...string) {
+	if client.logger == nil {
+		return
+	}
+	levels := client.logLevels()
+	level := levels.Call
+	message := "postageapp: api call"
+	if call.err != nil && retrying {
+		level = levels.Retry
+		message = "postageapp: api call failed, retrying"
+	} else if call.err != nil {
+		level = levels.Failure
+		message = "postageapp: api call failed"
+	}
+
+	attrs := []interface{}{
+		"endpoint", call.endpoint,
+		"uid", call.uid,
+		"attempt", call.attempt,
+		"duration", time.Since(call.started),
+	}
+	if call.httpStatus != 0 {
+		attrs = append(attrs, "http_status", call.httpStatus)
+	}
+	if status := responseStatus(call.response); status != "" {
+		attrs = append(attrs, "api_status", status)
+	}
+	if call.err != nil {
+		attrs = append(attrs, "error", redactError(call.err, keys...).Error())
+	}
+	client.logger.Log(context.Background(), level, message, attrs...)
+}
+
+func payloadUid(payload []byte) string {
+	var fields struct {
+		Uid string `json:"uid"`
+	}
+	json.Unmarshal(payload, &fields)
+	return fields.Uid
+}
+
+func RedactPII(payload []byte) []byte {
+	var decoded interface{}
+	if err := json.Unmarshal(payload, &decoded); err != nil {
+		return []byte(maskEmails(string(RedactPayload(payload))))
+	}
+	redacted, err := json.Marshal(redactValue("", decoded, false))
+	if err != nil {
+		return []byte(maskEmails(string(RedactPayload(payload))))
+	}
+	return redacted
+}
+
+func redactValue(key string, value interface{}, sensitive bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for name, item := range v {
+			switch {
+			case key == "recipients":
+				redacted[maskEmails(name)] = redactValue(name, item, true)
+			default:
+				redacted[name] = redactValue(name, item, sensitive || name == "variables")
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactValue(key, item, sensitive)
+		}
+		return redacted
+	case string:
+		switch {
+		case key == "api_key":
+			return maskApiKey(v)
+		case sensitive:
+			return "[redacted]"
+		case key == "content" || key == "text/plain" || key == "text/html":
+			return fmt.Sprintf("[%d bytes]", len(v))
+		}
+		return maskEmails(v)
+	}
+	if sensitive && value != nil {
+		return "[redacted]"
+	}
+	return value
+}
+
+func maskEmails(s string) string {
+	return emailPattern.ReplaceAllStringFunc(s, func(email string) string {
+		parts := emailPattern.FindStringSubmatch(email)
+		return parts[1][:1] + "***@" + parts[2]
+	})
+}
@@ -0,0 +1,108 @@
+package postage_app
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func logRecords(t *testing.T, logged *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(logged.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogCallAttempts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"response":{"status":"ok","uid":"abc"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cl, _ := NewClient(ApiKey, WithBaseURL(server.URL), WithLogger(logger), WithRetry(2, time.Millisecond, time.Millisecond),
+		WithLogLevels(slog.LevelDebug, slog.LevelInfo, slog.LevelError))
+
+	if _, err := cl.GetMessageReceipt("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	records := logRecords(t, &logged)
+	if len(records) != 2 {
+		t.Fatal(logged.String())
+	}
+	first, second := records[0], records[1]
+	if first["level"] != "INFO" || first["endpoint"] != "get_message_receipt" || first["uid"] != "abc" ||
+		first["attempt"] != float64(1) || first["http_status"] != float64(503) || first["error"] == nil {
+		t.Log(first)
+		t.Fail()
+	}
+	if second["level"] != "DEBUG" || second["attempt"] != float64(2) || second["http_status"] != float64(200) ||
+		second["api_status"] != "ok" || second["duration"] == nil {
+		t.Log(second)
+		t.Fail()
+	}
+}
+
+func TestLogPayloadRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cl, _ := NewClient(redactTestKey, WithBaseURL(server.URL), WithLogger(logger), WithPayloadLogging(true))
+
+	message := new(Message)
+	message.Recipients = []*Recipient{{"jane.doe@example.com", map[string]string{"name": "Jane Doe"}}}
+	message.Variables = map[string]string{"account": "12345678"}
+	message.Text = "Hello Jane"
+	message.From = "sender@example.org"
+	if _, err := cl.SendMessage(message); err != nil {
+		t.Fatal(err)
+	}
+
+	out := logged.String()
+	for _, secret := range []string{redactTestKey, "jane.doe@example.com", "Jane Doe", "12345678", "Hello Jane", "sender@example.org"} {
+		if strings.Contains(out, secret) {
+			t.Log(secret, out)
+			t.Fail()
+		}
+	}
+	if !strings.Contains(out, `j***@example.com`) || !strings.Contains(out, "request payload") {
+		t.Log(out)
+		t.Fail()
+	}
+}
+
+func TestLogPayloadOffByDefault(t *testing.T) {
+	var logged bytes.Buffer
+	cl := new(Client)
+	cl.logger = slog.New(slog.NewJSONHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	cl.logPayload("postageapp: request payload", "send_message", []byte(`{}`))
+	if logged.Len() != 0 {
+		t.Log(logged.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,124 @@
+package postage_app
+
+import (
+	"context"
+	"iter"
+	"sort"
+	"time"
+)
+
+type MessagesOptions struct {
+	Since       time.Time
+	Until       time.Time
+	Template    string
+	HasFailures bool
+	Offset      int
+	Limit       int
+}
+
+func (opts *MessagesOptions) Match(info *MessageInfo) bool {
+	if opts == nil {
+		return true
+	}
+	if !opts.Since.IsZero() && info.CreatedAt.Before(opts.Since) {
+		return false
+	}
+	if !opts.Until.IsZero() && !info.CreatedAt.Before(opts.Until) {
+		return false
+	}
+	if opts.Template != "" && info.Template != opts.Template {
+		return false
+	}
+	if opts.HasFailures && info.FailedTransmissionsCount == 0 {
+		return false
+	}
+	return true
+}
+
+func FilterMessages(messages map[string]*MessageInfo, opts *MessagesOptions) []*MessageInfo {
+	filtered := make([]*MessageInfo, 0, len(messages))
+	for uid, info := range messages {
+		if info.Uid == "" {
+			withUid := *info
+			withUid.Uid = uid
+			info = &withUid
+		}
+		if opts.Match(info) {
+			filtered = append(filtered, info)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool {
+		if !filtered[i].CreatedAt.Equal(filtered[j].CreatedAt) {
+			return filtered[i].CreatedAt.Before(filtered[j].CreatedAt)
+		}
+		return filtered[i].Uid < filtered[j].Uid
+	})
+
+	if opts == nil {
+		return filtered
+	}
+	if opts.Offset > 0 {
+		if opts.Offset >= len(filtered) {
+			return filtered[:0]
+		}
+		filtered = filtered[opts.Offset:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(filtered) {
+		filtered = filtered[:opts.Limit]
+	}
+	return filtered
+}
+
+func (client *Client) ListMessages(ctx context.Context, opts *MessagesOptions) ([]*MessageInfo, error) {
+	response, err := client.GetMessagesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return FilterMessages(response.Data, opts), nil
+}
+
+func (client *Client) Messages(ctx context.Context, opts *MessagesOptions) iter.Seq2[*MessageInfo, error] {
+	return func(yield func(*MessageInfo, error) bool) {
+		messages, err := client.ListMessages(ctx, opts)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, info := range messages {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(info, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (info *MessageInfo) PendingTransmissionsCount() int {
+	return max(info.TotalTransmissionsCount-info.CompletedTransmissionsCount-info.FailedTransmissionsCount, 0)
+}
+
+func (info *MessageInfo) IsPurged(now time.Time) bool {
+	return !info.WillPurgeAt.IsZero() && !now.Before(info.WillPurgeAt)
+}
+
+func (info *MessageInfo) Transmissions(ctx context.Context, client *Client) (*MessageTransmissionsResponse, error) {
+	if info.Uid == "" {
+		return nil, &PostageError{"message info has no uid", nil}
+	}
+	return client.GetMessageTransmissionsContext(ctx, info.Uid)
+}
+
+func (response *MessagesResponse) Messages() []*MessageInfo {
+	return FilterMessages(response.Data, nil)
+}
+
+func (response *MessagesResponse) SortedBy(less func(a *MessageInfo, b *MessageInfo) bool) []*MessageInfo {
+	messages := response.Messages()
+	sort.SliceStable(messages, func(i, j int) bool {
+		return less(messages[i], messages[j])
+	})
+	return messages
+}
@@ -0,0 +1,136 @@
+package postage_app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func InitMessageInfos() map[string]*MessageInfo {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	return map[string]*MessageInfo{
+		"c": {Template: "welcome", CreatedAt: base.Add(2 * time.Hour)},
+		"a": {Template: "welcome", CreatedAt: base, FailedTransmissionsCount: 1},
+		"d": {Template: "receipt", CreatedAt: base.Add(3 * time.Hour), FailedTransmissionsCount: 2},
+		"b": {Template: "receipt", CreatedAt: base.Add(time.Hour)},
+	}
+}
+
+func uids(infos []*MessageInfo) string {
+	s := ""
+	for _, info := range infos {
+		s += info.Uid
+	}
+	return s
+}
+
+func TestFilterMessages(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		opts     *MessagesOptions
+		expected string
+	}{
+		{nil, "abcd"},
+		{&MessagesOptions{Template: "receipt"}, "bd"},
+		{&MessagesOptions{HasFailures: true}, "ad"},
+		{&MessagesOptions{Since: base.Add(time.Hour), Until: base.Add(3 * time.Hour)}, "bc"},
+		{&MessagesOptions{Offset: 1, Limit: 2}, "bc"},
+		{&MessagesOptions{Offset: 9}, ""},
+	}
+	for _, c := range cases {
+		if got := uids(FilterMessages(InitMessageInfos(), c.opts)); got != c.expected {
+			t.Log(c.opts, got, c.expected)
+			t.Fail()
+		}
+	}
+
+	messages := InitMessageInfos()
+	FilterMessages(messages, nil)
+	for uid, info := range messages {
+		if info.Uid != "" {
+			t.Log("input was modified", uid, info.Uid)
+			t.Fail()
+		}
+	}
+}
+
+func TestMessagesIterator(t *testing.T) {
+	cl, _, message := InitSandboxMessage()
+	for _, uid := range []string{"a1", "b2", "c3"} {
+		message.Uid = uid
+		if _, err := cl.SendMessage(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var seen []string
+	for info, err := range cl.Messages(context.Background(), nil) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, info.Uid)
+		if len(seen) == 2 {
+			break
+		}
+	}
+	if len(seen) != 2 || seen[0] != "a1" || seen[1] != "b2" {
+		t.Log(seen)
+		t.Fail()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for info, err := range cl.Messages(ctx, nil) {
+		if info != nil || !errors.Is(err, context.Canceled) {
+			t.Log(info, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestMessageInfoHelpers(t *testing.T) {
+	base := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	info := &MessageInfo{TotalTransmissionsCount: 5, CompletedTransmissionsCount: 2, FailedTransmissionsCount: 1, WillPurgeAt: base}
+	if info.PendingTransmissionsCount() != 2 {
+		t.Log(info.PendingTransmissionsCount())
+		t.Fail()
+	}
+	if info.IsPurged(base.Add(-time.Second)) || !info.IsPurged(base) || new(MessageInfo).IsPurged(base) {
+		t.Fail()
+	}
+
+	response := &MessagesResponse{Data: InitMessageInfos()}
+	if got := uids(response.Messages()); got != "abcd" {
+		t.Log(got)
+		t.Fail()
+	}
+	byFailures := response.SortedBy(func(a *MessageInfo, b *MessageInfo) bool {
+		return a.FailedTransmissionsCount > b.FailedTransmissionsCount
+	})
+	if got := uids(byFailures); got != "dabc" {
+		t.Log(got)
+		t.Fail()
+	}
+}
+
+func TestMessageInfoFromSandbox(t *testing.T) {
+	cl, _, message := InitSandboxMessage()
+	cl.SendMessage(message)
+
+	response, err := cl.GetMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	messages := response.Messages()
+	if len(messages) != 1 || messages[0].Uid != message.Uid || messages[0].Id != 1 {
+		t.Log(messages)
+		t.Fatal()
+	}
+
+	transmissions, err := messages[0].Transmissions(context.Background(), cl)
+	if err != nil || transmissions.Data.Id != 1 {
+		t.Log(transmissions, err)
+		t.Fail()
+	}
+}
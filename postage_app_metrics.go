@@ -0,0 +1,163 @@
+package postage_app
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+)
+
+var MetricPeriods = []string{"hour", "date", "week", "month"}
+
+var MetricStatistics = []string{"created", "queued", "delivered", "opened", "clicked", "failed", "rejected", "spammed"}
+
+func metricNumber(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case json.Number:
+		f, err := value.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func (metric *Metric) setStatistic(name string, statistic *MetricStatistic) {
+	switch name {
+	case "delivered":
+		metric.Delivered = statistic
+	case "opened":
+		metric.Opened = statistic
+	case "failed":
+		metric.Failed = statistic
+	case "rejected":
+		metric.Rejected = statistic
+	case "created":
+		metric.Created = statistic
+	case "queued":
+		metric.Queued = statistic
+	case "clicked":
+		metric.Clicked = statistic
+	case "spammed":
+		metric.Spammed = statistic
+	default:
+		if metric.Extra == nil {
+			metric.Extra = make(map[string]*MetricStatistic)
+		}
+		metric.Extra[name] = statistic
+	}
+}
+
+func (metric *Metric) Statistic(name string) *MetricStatistic {
+	switch name {
+	case "delivered":
+		return metric.Delivered
+	case "opened":
+		return metric.Opened
+	case "failed":
+		return metric.Failed
+	case "rejected":
+		return metric.Rejected
+	case "created":
+		return metric.Created
+	case "queued":
+		return metric.Queued
+	case "clicked":
+		return metric.Clicked
+	case "spammed":
+		return metric.Spammed
+	}
+	return metric.Extra[name]
+}
+
+func (metric *Metric) Names() []string {
+	var names []string
+	for _, name := range MetricStatistics {
+		if metric.Statistic(name) != nil {
+			names = append(names, name)
+		}
+	}
+	extra := make([]string, 0, len(metric.Extra))
+	for name := range metric.Extra {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+func (metric *Metric) Each(f func(name string, statistic *MetricStatistic)) {
+	for _, name := range metric.Names() {
+		f(name, metric.Statistic(name))
+	}
+}
+
+func (metrics *Metrics) setPeriod(name string, metric *Metric) {
+	switch name {
+	case "hour":
+		metrics.Hour = metric
+	case "date":
+		metrics.Date = metric
+	case "week":
+		metrics.Week = metric
+	case "month":
+		metrics.Month = metric
+	default:
+		if metrics.Extra == nil {
+			metrics.Extra = make(map[string]*Metric)
+		}
+		metrics.Extra[name] = metric
+	}
+}
+
+func (metrics *Metrics) Period(name string) *Metric {
+	switch name {
+	case "hour":
+		return metrics.Hour
+	case "date":
+		return metrics.Date
+	case "week":
+		return metrics.Week
+	case "month":
+		return metrics.Month
+	}
+	return metrics.Extra[name]
+}
+
+func (metrics *Metrics) Periods() []string {
+	var names []string
+	for _, name := range MetricPeriods {
+		if metrics.Period(name) != nil {
+			names = append(names, name)
+		}
+	}
+	extra := make([]string, 0, len(metrics.Extra))
+	for name := range metrics.Extra {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+func (metrics *Metrics) Each(f func(period string, metric *Metric)) {
+	for _, name := range metrics.Periods() {
+		f(name, metrics.Period(name))
+	}
+}
+
+func (statistic *MetricStatistic) Field(name string) (float64, bool) {
+	switch name {
+	case "current_percent":
+		return statistic.CurrentPercent, statistic.Valid.CurrentPercent
+	case "previous_percent":
+		return statistic.PreviousPercent, statistic.Valid.PreviousPercent
+	case "diff_percent":
+		return statistic.DiffPercent, statistic.Valid.DiffPercent
+	case "current_value":
+		return float64(statistic.CurrentValue), statistic.Valid.CurrentValue
+	case "previous_value":
+		return float64(statistic.PreviousValue), statistic.Valid.PreviousValue
+	}
+	return 0, false
+}
@@ -0,0 +1,71 @@
+package postage_app
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+type Call struct {
+	Endpoint      string
+	Request       interface{}
+	Context       context.Context
+	Body          []byte
+	Header        http.Header
+	HTTPRequest   *http.Request
+	HTTPResponse  *http.Response
+	ResponseBody  []byte
+	Attempts      int
+	RateLimitWait time.Duration
+	OnRetry       func(attempt int, delay time.Duration, err error)
+}
+
+type Handler func(call *Call) (map[string]interface{}, error)
+
+type Middleware func(next Handler) Handler
+
+func (client *Client) chain() []Middleware {
+	if chain, ok := client.middleware.Load().(*[]Middleware); ok {
+		return *chain
+	}
+	return nil
+}
+
+func (client *Client) Use(middleware ...Middleware) {
+	for {
+		current, _ := client.middleware.Load().(*[]Middleware)
+		var chain []Middleware
+		if current != nil {
+			chain = append(chain, *current...)
+		}
+		chain = append(chain, middleware...)
+
+		var old interface{}
+		if current != nil {
+			old = current
+		}
+		if client.middleware.CompareAndSwap(old, &chain) {
+			return
+		}
+	}
+}
+
+func (client *Client) invoke(call *Call) (map[string]interface{}, error) {
+	chain := client.chain()
+	handler := Handler(client.dispatch)
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = chain[i](handler)
+	}
+	return handler(call)
+}
+
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next Handler) Handler {
+		return func(call *Call) (map[string]interface{}, error) {
+			for name, values := range header {
+				call.Header[http.CanonicalHeaderKey(name)] = values
+			}
+			return next(call)
+		}
+	}
+}
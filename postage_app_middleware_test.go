@@ -0,0 +1,158 @@
+package postage_app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareOrderAndExchange(t *testing.T) {
+	var proxyAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxyAuth = r.Header.Get("Proxy-Authorization")
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":7,"url":"http://x"}}}`))
+	}))
+	defer server.Close()
+
+	var order []string
+	var seen *Call
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(call *Call) (map[string]interface{}, error) {
+				order = append(order, name+">")
+				m, err := next(call)
+				order = append(order, "<"+name)
+				seen = call
+				return m, err
+			}
+		}
+	}
+
+	cl, _ := NewClient(ApiKey, WithBaseURL(server.URL),
+		WithMiddleware(trace("outer"), HeaderMiddleware(http.Header{"proxy-authorization": {"Basic abc"}})),
+		WithMiddleware(trace("inner")))
+
+	response, err := cl.GetMessageReceipt("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Data.Id != 7 || proxyAuth != "Basic abc" {
+		t.Log(response.Data, proxyAuth)
+		t.Fail()
+	}
+	if len(order) != 4 || order[0] != "outer>" || order[1] != "inner>" || order[2] != "<inner" || order[3] != "<outer" {
+		t.Log(order)
+		t.Fail()
+	}
+	if seen.Endpoint != "get_message_receipt" || seen.Request != "abc" || seen.Attempts != 1 ||
+		seen.HTTPRequest == nil || seen.HTTPResponse == nil || seen.HTTPResponse.StatusCode != 200 || len(seen.ResponseBody) == 0 {
+		t.Log(seen)
+		t.Fail()
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	injected := errors.New("injected fault")
+	cl, _ := NewClient(ApiKey, WithBaseURL("http://127.0.0.1:1/"), WithMiddleware(func(next Handler) Handler {
+		return func(call *Call) (map[string]interface{}, error) {
+			if _, ok := call.Request.(*Message); ok {
+				return nil, injected
+			}
+			return map[string]interface{}{"response": map[string]interface{}{"status": "ok"}, "data": map[string]interface{}{}}, nil
+		}
+	}))
+
+	_, _, message := InitSandboxMessage()
+	if _, err := cl.SendMessage(message); err != injected {
+		t.Log(err)
+		t.Fail()
+	}
+	if response, err := cl.GetMessages(); err != nil || response.Response.Status != "ok" || len(response.Data) != 0 {
+		t.Log(response, err)
+		t.Fail()
+	}
+}
+
+func TestMiddlewareModifiesResponse(t *testing.T) {
+	cl, sink, message := InitSandboxMessage()
+	cl.Use(func(next Handler) Handler {
+		return func(call *Call) (map[string]interface{}, error) {
+			m, err := next(call)
+			if err == nil {
+				m["data"].(map[string]interface{})["message"].(map[string]interface{})["url"] = "rewritten"
+			}
+			return m, err
+		}
+	})
+
+	response, err := cl.SendMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Data.Url != "rewritten" || len(sink.Records()) != 1 {
+		t.Log(response.Data)
+		t.Fail()
+	}
+}
+
+func TestMiddlewareUseConcurrently(t *testing.T) {
+	cl, _, _ := InitSandboxMessage()
+	var calls atomic.Int32
+	counting := func(next Handler) Handler {
+		return func(call *Call) (map[string]interface{}, error) {
+			calls.Add(1)
+			return next(call)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cl.Use(counting)
+		}()
+		go func() {
+			defer wg.Done()
+			cl.GetMessages()
+		}()
+	}
+	wg.Wait()
+
+	calls.Store(0)
+	cl.GetMessages()
+	if len(cl.chain()) != 10 || calls.Load() != 10 {
+		t.Log(len(cl.chain()), calls.Load())
+		t.Fail()
+	}
+}
+
+func TestCallContextAndRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	var retries []int
+	ctx, cancel := context.WithCancel(context.Background())
+	cl, _ := NewClient(ApiKey, WithBaseURL(server.URL), WithRetry(5, time.Hour, time.Hour), WithMiddleware(func(next Handler) Handler {
+		return func(call *Call) (map[string]interface{}, error) {
+			call.OnRetry = func(attempt int, delay time.Duration, err error) {
+				retries = append(retries, attempt)
+				cancel()
+			}
+			return next(call)
+		}
+	}))
+
+	_, err := cl.GetMetricsContext(ctx)
+	if err == nil || len(retries) != 1 || retries[0] != 2 {
+		t.Log(err, retries)
+		t.Fail()
+	}
+}
@@ -0,0 +1,401 @@
+package postage_app
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+var mimeStructuralHeaders = map[string]bool{
+	"Subject":                   true,
+	"From":                      true,
+	"Reply-To":                  true,
+	"To":                        true,
+	"Cc":                        true,
+	"Bcc":                       true,
+	"Date":                      true,
+	"Message-Id":                true,
+	"Mime-Version":              true,
+	"Content-Type":              true,
+	"Content-Transfer-Encoding": true,
+	"Content-Disposition":       true,
+	"Received":                  true,
+	"Return-Path":               true,
+}
+
+func ParseMIME(r io.Reader) (*Message, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, &PostageError{"mime: " + err.Error(), err}
+	}
+
+	message := new(Message)
+	message.Uid = strings.Trim(strings.TrimSpace(msg.Header.Get("Message-Id")), "<>")
+	message.Subject = decodeMIMEHeader(msg.Header.Get("Subject"))
+	message.From = decodeMIMEHeader(msg.Header.Get("From"))
+	message.ReplyTo = decodeMIMEHeader(msg.Header.Get("Reply-To"))
+
+	for _, key := range []string{"To", "Cc", "Bcc"} {
+		if msg.Header.Get(key) == "" {
+			continue
+		}
+		addresses, err := msg.Header.AddressList(key)
+		if err != nil {
+			return nil, &PostageError{"mime: invalid " + key + " header: " + err.Error(), err}
+		}
+		for _, address := range addresses {
+			recipient := new(Recipient)
+			recipient.Email = address.String()
+			message.Recipients = append(message.Recipients, recipient)
+		}
+	}
+
+	for key, values := range msg.Header {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		if mimeStructuralHeaders[key] || len(values) == 0 {
+			continue
+		}
+		if message.Headers == nil {
+			message.Headers = make(map[string]string)
+		}
+		message.Headers[key] = decodeMIMEHeader(values[0])
+	}
+
+	err = parseMIMEPart(message, textproto.MIMEHeader(msg.Header), msg.Body)
+	if err != nil {
+		return nil, &PostageError{"mime: " + err.Error(), err}
+	}
+	return message, nil
+}
+
+func decodeMIMEHeader(value string) string {
+	decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
+func parseMIMEPart(message *Message, header textproto.MIMEHeader, body io.Reader) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+		params = map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := parseMIMEPart(message, part.Header, part); err != nil {
+				return err
+			}
+		}
+	}
+
+	content, err := ioutil.ReadAll(decodeMIMEBody(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	fileName := decodeMIMEHeader(dispositionParams["filename"])
+	if fileName == "" {
+		fileName = decodeMIMEHeader(params["name"])
+	}
+	contentId := strings.Trim(header.Get("Content-Id"), "<> ")
+
+	if disposition != "attachment" && fileName == "" && contentId == "" {
+		switch {
+		case mediaType == "text/plain" && message.Text == "":
+			message.Text = decodeCharset(content, params["charset"])
+			return nil
+		case mediaType == "text/html" && message.Html == "":
+			message.Html = decodeCharset(content, params["charset"])
+			return nil
+		}
+	}
+
+	attachment := new(Attachment)
+	attachment.FileName = fileName
+	if attachment.FileName == "" {
+		name := contentId
+		if name == "" {
+			name = "attachment"
+		}
+		attachment.FileName = name + mimeExtension(mediaType)
+	}
+	attachment.ContentType = mediaType
+	attachment.ContentBytes = content
+	message.Attachments = append(message.Attachments, attachment)
+	return nil
+}
+
+func decodeMIMEBody(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+func decodeCharset(content []byte, charset string) string {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1":
+		runes := make([]rune, len(content))
+		for i, b := range content {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	return string(content)
+}
+
+func mimeExtension(mediaType string) string {
+	extensions, _ := mime.ExtensionsByType(mediaType)
+	if len(extensions) > 0 {
+		return extensions[0]
+	}
+	return ".bin"
+}
+
+func (message *Message) WriteMIME(w io.Writer, recipient *Recipient) error {
+	text := message.Text
+	html := message.Html
+	subject := message.Subject
+	if message.Template == "" {
+		variables := message.recipientVariables(recipient)
+		text = renderPlaceholders(text, variables, nil)
+		html = renderPlaceholders(html, variables, nil)
+		subject = renderPlaceholders(subject, variables, nil)
+	}
+
+	header := make(textproto.MIMEHeader)
+	if message.From != "" {
+		header.Set("From", encodeAddressHeader(message.From))
+	}
+	if recipient != nil && recipient.Email != "" {
+		header.Set("To", encodeAddressHeader(recipient.Email))
+	}
+	if message.ReplyTo != "" {
+		header.Set("Reply-To", encodeAddressHeader(message.ReplyTo))
+	}
+	if subject != "" {
+		header.Set("Subject", mime.QEncoding.Encode("utf-8", subject))
+	}
+	if message.Uid != "" {
+		header.Set("Message-Id", "<"+messageIdFor(message.Uid)+">")
+	}
+	for key, value := range message.Headers {
+		key = textproto.CanonicalMIMEHeaderKey(key)
+		switch key {
+		case "From", "To", "Reply-To", "Cc", "Bcc":
+			header.Set(key, encodeAddressHeader(value))
+		default:
+			header.Set(key, mime.QEncoding.Encode("utf-8", value))
+		}
+	}
+	header.Set("Mime-Version", "1.0")
+
+	bw := bufio.NewWriter(w)
+	seed := boundarySeed(message, recipient)
+
+	var err error
+	if len(message.Attachments) == 0 {
+		open := func(header textproto.MIMEHeader) (io.Writer, error) {
+			return bw, writeMIMEHeader(bw, header)
+		}
+		err = writeMIMEBody(header, open, text, html, seed)
+	} else {
+		err = writeMIMEMixed(bw, header, text, html, message.Attachments, seed)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func (message *Message) withDate(date time.Time) *Message {
+	for key := range message.Headers {
+		if textproto.CanonicalMIMEHeaderKey(key) == "Date" {
+			return message
+		}
+	}
+	dated := *message
+	dated.Headers = make(map[string]string, len(message.Headers)+1)
+	for key, value := range message.Headers {
+		dated.Headers[key] = value
+	}
+	dated.Headers["Date"] = date.Format(time.RFC1123Z)
+	return &dated
+}
+
+func writeMIMEMixed(w io.Writer, header textproto.MIMEHeader, text string, html string, attachments []*Attachment, seed string) error {
+	boundary := mimeBoundary(seed, "mixed")
+	header.Set("Content-Type", "multipart/mixed; boundary="+boundary)
+	if err := writeMIMEHeader(w, header); err != nil {
+		return err
+	}
+	mixed := multipart.NewWriter(w)
+	mixed.SetBoundary(boundary)
+
+	if text != "" || html != "" {
+		open := func(partHeader textproto.MIMEHeader) (io.Writer, error) {
+			return mixed.CreatePart(partHeader)
+		}
+		if err := writeMIMEBody(make(textproto.MIMEHeader), open, text, html, seed); err != nil {
+			return err
+		}
+	}
+
+	for _, attachment := range attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		partHeader := make(textproto.MIMEHeader)
+		partHeader.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": attachment.FileName}))
+		partHeader.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+		partHeader.Set("Content-Transfer-Encoding", "base64")
+		part, err := mixed.CreatePart(partHeader)
+		if err != nil {
+			return err
+		}
+		if err := writeBase64Lines(part, attachment.ContentBytes); err != nil {
+			return err
+		}
+	}
+	return mixed.Close()
+}
+
+func writeMIMEBody(header textproto.MIMEHeader, open func(textproto.MIMEHeader) (io.Writer, error), text string, html string, seed string) error {
+	if text != "" && html != "" {
+		boundary := mimeBoundary(seed, "alternative")
+		header.Set("Content-Type", "multipart/alternative; boundary="+boundary)
+		w, err := open(header)
+		if err != nil {
+			return err
+		}
+		alternative := multipart.NewWriter(w)
+		alternative.SetBoundary(boundary)
+		for _, body := range []struct{ mediaType, content string }{{"text/plain", text}, {"text/html", html}} {
+			part, err := alternative.CreatePart(textPartHeader(body.mediaType))
+			if err != nil {
+				return err
+			}
+			if err := writeQuotedPrintable(part, body.content); err != nil {
+				return err
+			}
+		}
+		return alternative.Close()
+	}
+
+	mediaType, content := "text/plain", text
+	if html != "" {
+		mediaType, content = "text/html", html
+	}
+	for key, values := range textPartHeader(mediaType) {
+		header[key] = values
+	}
+	w, err := open(header)
+	if err != nil {
+		return err
+	}
+	return writeQuotedPrintable(w, content)
+}
+
+func textPartHeader(mediaType string) textproto.MIMEHeader {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Type", mediaType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return header
+}
+
+func writeMIMEHeader(w io.Writer, header textproto.MIMEHeader) error {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range header[key] {
+			if _, err := fmt.Fprintf(w, "%s: %s\r\n", key, value); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeBase64Lines(w io.Writer, content []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(w, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err := io.WriteString(w, encoded+"\r\n")
+	return err
+}
+
+func encodeAddressHeader(value string) string {
+	addresses, err := mail.ParseAddressList(value)
+	if err != nil {
+		return mime.QEncoding.Encode("utf-8", value)
+	}
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = address.String()
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func messageIdFor(uid string) string {
+	if strings.Contains(uid, "@") {
+		return uid
+	}
+	return uid + "@postageapp.com"
+}
+
+func boundarySeed(message *Message, recipient *Recipient) string {
+	seed := message.Uid
+	if recipient != nil {
+		seed += "/" + recipient.Email
+	}
+	return seed
+}
+
+func mimeBoundary(seed string, kind string) string {
+	sum := sha1.Sum([]byte(seed + "/" + kind))
+	return kind + "-" + hex.EncodeToString(sum[:12])
+}
@@ -0,0 +1,273 @@
+package postage_app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const emlMessage = "From: =?ISO-8859-1?Q?Andr=E9?= <andre@acme.com>\r\n" +
+	"Reply-To: Acme Support <support@acme.com>\r\n" +
+	"To: Alan Smithee <alan.smithee@gmail.com>, rick.james@gmail.com\r\n" +
+	"Cc: Cloudy <cloudy@mailinator.com>\r\n" +
+	"Subject: =?UTF-8?B?WW91ciBvcmRlciDimIM=?=\r\n" +
+	"Message-ID: <order-555@acme.com>\r\n" +
+	"X-Accept-Language: en-us, en\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=mixed\r\n" +
+	"\r\n" +
+	"--mixed\r\n" +
+	"Content-Type: multipart/related; boundary=related\r\n" +
+	"\r\n" +
+	"--related\r\n" +
+	"Content-Type: multipart/alternative; boundary=alternative\r\n" +
+	"\r\n" +
+	"--alternative\r\n" +
+	"Content-Type: text/plain; charset=iso-8859-1\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Caf=E9 order =3D shipped\r\n" +
+	"--alternative\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"PHA+T3JkZXIgc2hpcHBlZDwvcD4=\r\n" +
+	"--alternative--\r\n" +
+	"--related\r\n" +
+	"Content-Type: image/png\r\n" +
+	"Content-ID: <logo@acme.com>\r\n" +
+	"Content-Disposition: inline; filename=logo.png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--related--\r\n" +
+	"--mixed\r\n" +
+	"Content-Type: application/pdf\r\n" +
+	"Content-Disposition: attachment; filename=\"=?UTF-8?Q?invoice_=E2=98=83.pdf?=\"\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"JVBERi0xLjQ=\r\n" +
+	"--mixed--\r\n"
+
+func TestParseMIMEHeaders(t *testing.T) {
+	message, err := ParseMIME(strings.NewReader(emlMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Uid != "order-555@acme.com" {
+		t.Log(message.Uid)
+		t.Fail()
+	}
+
+	if message.Subject != "Your order ☃" {
+		t.Log(message.Subject)
+		t.Fail()
+	}
+
+	if message.From != "André <andre@acme.com>" {
+		t.Log(message.From)
+		t.Fail()
+	}
+
+	if message.ReplyTo != "Acme Support <support@acme.com>" {
+		t.Log(message.ReplyTo)
+		t.Fail()
+	}
+
+	if len(message.Recipients) != 3 {
+		t.Log(message.Recipients)
+		t.Fail()
+	} else if message.Recipients[0].Email != `"Alan Smithee" <alan.smithee@gmail.com>` || message.Recipients[2].Email != `"Cloudy" <cloudy@mailinator.com>` {
+		t.Log(message.Recipients[0].Email, message.Recipients[2].Email)
+		t.Fail()
+	}
+
+	if len(message.Headers) != 1 || message.Headers["X-Accept-Language"] != "en-us, en" {
+		t.Log(message.Headers)
+		t.Fail()
+	}
+}
+
+func TestParseMIMEBodies(t *testing.T) {
+	message, err := ParseMIME(strings.NewReader(emlMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Text != "Café order = shipped" {
+		t.Log(message.Text)
+		t.Fail()
+	}
+
+	if message.Html != "<p>Order shipped</p>" {
+		t.Log(message.Html)
+		t.Fail()
+	}
+
+	if len(message.Attachments) != 2 {
+		t.Fatal(len(message.Attachments))
+	}
+
+	logo := message.Attachments[0]
+	if logo.FileName != "logo.png" || logo.ContentType != "image/png" || string(logo.ContentBytes) != "\x89PNG\r\n\x1a\n" {
+		t.Log(logo.FileName, logo.ContentType, logo.ContentBytes)
+		t.Fail()
+	}
+
+	invoice := message.Attachments[1]
+	if invoice.FileName != "invoice ☃.pdf" || string(invoice.ContentBytes) != "%PDF-1.4" {
+		t.Log(invoice.FileName, string(invoice.ContentBytes))
+		t.Fail()
+	}
+}
+
+func TestParseMIMESinglePart(t *testing.T) {
+	message, err := ParseMIME(strings.NewReader("From: widgets@acme.com\r\nSubject: Plain\r\n\r\nJust text.\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Text != "Just text.\r\n" {
+		t.Log(message.Text)
+		t.Fail()
+	}
+
+	if message.Uid != "" || len(message.Attachments) != 0 {
+		t.Log(message.Uid, message.Attachments)
+		t.Fail()
+	}
+}
+
+func TestParseMIMEInvalid(t *testing.T) {
+	_, err := ParseMIME(strings.NewReader("not a message"))
+	if err == nil {
+		t.Log("Error is nil")
+		t.Fail()
+	}
+}
+
+func TestWriteMIMERoundTrip(t *testing.T) {
+	_, message := InitMessage()
+	message.From = "André <andre@acme.com>"
+	message.ReplyTo = "support@acme.com"
+	message.Subject = "Order {{order_id}} ☃"
+	message.Text = "Hello {{first_name}}, order {{order_id}} shipped. {{unknown}}"
+	message.Html = "<p>Hello {{ first_name }}</p>"
+	message.Variables = map[string]string{"first_name": "Friend", "order_id": "555"}
+	message.Headers = map[string]string{"X-Accept-Language": "en-us, en", "Date": "Thu, 21 Mar 2013 17:13:21 +0000"}
+	attachment := new(Attachment)
+	attachment.FileName = "readme.txt"
+	attachment.ContentType = "text/plain"
+	attachment.ContentBytes = []byte("file contents!\n\n")
+	message.Attachments = append(message.Attachments, attachment)
+
+	recipient := new(Recipient)
+	recipient.Email = "Alan Smithee <alan.smithee@gmail.com>"
+	recipient.Variables = map[string]string{"first_name": "Alan"}
+
+	var b strings.Builder
+	if err := message.WriteMIME(&b, recipient); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseMIME(strings.NewReader(b.String()))
+	if err != nil {
+		t.Log(b.String())
+		t.Fatal(err)
+	}
+
+	if parsed.Uid != message.Uid+"@postageapp.com" {
+		t.Log(parsed.Uid)
+		t.Fail()
+	}
+
+	if parsed.Subject != "Order 555 ☃" {
+		t.Log(parsed.Subject)
+		t.Fail()
+	}
+
+	if parsed.From != message.From {
+		t.Log(parsed.From)
+		t.Fail()
+	}
+
+	if len(parsed.Recipients) != 1 || parsed.Recipients[0].Email != `"Alan Smithee" <alan.smithee@gmail.com>` {
+		t.Log(parsed.Recipients)
+		t.Fail()
+	}
+
+	if parsed.Text != "Hello Alan, order 555 shipped. {{unknown}}" {
+		t.Log(parsed.Text)
+		t.Fail()
+	}
+
+	if parsed.Html != "<p>Hello Alan</p>" {
+		t.Log(parsed.Html)
+		t.Fail()
+	}
+
+	if parsed.Headers["X-Accept-Language"] != "en-us, en" {
+		t.Log(parsed.Headers)
+		t.Fail()
+	}
+
+	if len(parsed.Attachments) != 1 || string(parsed.Attachments[0].ContentBytes) != "file contents!\n\n" {
+		t.Log(parsed.Attachments)
+		t.Fail()
+	}
+
+	var again strings.Builder
+	message.WriteMIME(&again, recipient)
+	if again.String() != b.String() {
+		t.Log("Output is not deterministic")
+		t.Fail()
+	}
+}
+
+func TestWriteMIMEKeepsPlaceholdersForTemplates(t *testing.T) {
+	_, message := InitMessage()
+	message.Template = "some-template-slug"
+	message.Text = "Hello {{first_name}}"
+	message.Variables = map[string]string{"first_name": "Alan"}
+
+	var b strings.Builder
+	if err := message.WriteMIME(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(b.String(), "Content-Type: text/plain; charset=utf-8\r\n") || !strings.Contains(b.String(), "Hello {{first_name}}") {
+		t.Log(b.String())
+		t.Fail()
+	}
+
+	if strings.Contains(b.String(), "multipart/") {
+		t.Log(b.String())
+		t.Fail()
+	}
+}
+
+func TestWriteMIMEWithoutDateIsDeterministic(t *testing.T) {
+	_, message := InitMessage()
+	message.Subject = "Hello"
+	message.Text = "Hello {{first_name}}"
+	message.Html = "<p>Hello {{first_name}}</p>"
+	message.Variables = map[string]string{"first_name": "Alan"}
+
+	var b strings.Builder
+	if err := message.WriteMIME(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(b.String(), "Date:") {
+		t.Log(b.String())
+		t.Fail()
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	var again strings.Builder
+	message.WriteMIME(&again, nil)
+	if again.String() != b.String() {
+		t.Log("Output is not deterministic")
+		t.Fail()
+	}
+}
@@ -0,0 +1,169 @@
+package postage_app
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const DefaultUserAgent = "postageapp-go"
+
+type Option func(client *Client) error
+
+func NewClient(apiKey string, opts ...Option) (*Client, error) {
+	client := new(Client)
+	client.ApiKey = apiKey
+	for _, opt := range opts {
+		if err := opt(client); err != nil {
+			return nil, err
+		}
+	}
+	if client.ApiKey == "" && client.keyRotation == nil {
+		return nil, &PostageError{"api key is required", nil}
+	}
+	if client.httpClient == nil {
+		client.httpClient = &http.Client{Timeout: client.timeout}
+	}
+	return client, nil
+}
+
+func WithBaseURL(baseUrl string) Option {
+	return func(client *Client) error {
+		u, err := url.Parse(baseUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return &PostageError{"invalid base URL " + baseUrl, err}
+		}
+		if !strings.HasSuffix(baseUrl, "/") {
+			baseUrl += "/"
+		}
+		client.BaseUrl = baseUrl
+		return nil
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(client *Client) error {
+		if httpClient == nil {
+			return &PostageError{"http client must not be nil", nil}
+		}
+		client.httpClient = httpClient
+		return nil
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(client *Client) error {
+		if timeout < 0 {
+			return &PostageError{"timeout must not be negative", nil}
+		}
+		client.timeout = timeout
+		if client.httpClient != nil {
+			httpClient := *client.httpClient
+			httpClient.Timeout = timeout
+			client.httpClient = &httpClient
+		}
+		return nil
+	}
+}
+
+func WithRetry(maxAttempts int, backoff time.Duration, maxBackoff time.Duration) Option {
+	return func(client *Client) error {
+		if maxAttempts < 1 || backoff < 0 || maxBackoff < 0 {
+			return &PostageError{"invalid retry policy", nil}
+		}
+		client.retry = &RetryPolicy{maxAttempts, backoff, maxBackoff}
+		return nil
+	}
+}
+
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(client *Client) error {
+		if perSecond <= 0 {
+			return &PostageError{"rate limit must be positive", nil}
+		}
+		client.rateLimiter = NewRateLimiter(perSecond, burst)
+		return nil
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(client *Client) error {
+		client.logger = logger
+		return nil
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(client *Client) error {
+		client.userAgent = userAgent
+		return nil
+	}
+}
+
+func WithRecipientPolicy(policy *RecipientPolicy) Option {
+	return func(client *Client) error {
+		client.recipientPolicy = policy
+		return nil
+	}
+}
+
+func WithSandbox(sandbox *Sandbox) Option {
+	return func(client *Client) error {
+		client.sandbox = sandbox
+		return nil
+	}
+}
+
+func WithKeyProvider(provider KeyProvider) Option {
+	return func(client *Client) error {
+		if provider == nil {
+			return &PostageError{"key provider must not be nil", nil}
+		}
+		client.keyRotation = NewKeyRotation(provider)
+		return nil
+	}
+}
+
+func WithLogLevels(call slog.Level, retry slog.Level, failure slog.Level) Option {
+	return func(client *Client) error {
+		client.levels = &LogLevels{call, retry, failure}
+		return nil
+	}
+}
+
+func WithPayloadLogging(enabled bool) Option {
+	return func(client *Client) error {
+		client.logPayloads = enabled
+		return nil
+	}
+}
+
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(client *Client) error {
+		client.Use(middleware...)
+		return nil
+	}
+}
+
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(client *Client) error {
+		client.circuitBreaker = breaker
+		return nil
+	}
+}
+
+func WithOutbox(outbox *Outbox) Option {
+	return func(client *Client) error {
+		client.outbox = outbox
+		return nil
+	}
+}
+
+func WithSuppressionList(list *SuppressionList) Option {
+	return func(client *Client) error {
+		client.suppressions = list
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package postage_app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewClientOptions(t *testing.T) {
+	httpClient := &http.Client{}
+	cl, err := NewClient(ApiKey,
+		WithBaseURL("https://api.example.com/v.1.0"),
+		WithHTTPClient(httpClient),
+		WithTimeout(5*time.Second),
+		WithRetry(3, time.Second, 10*time.Second),
+		WithRateLimit(10, 5),
+		WithUserAgent("acme-widgets/1.0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cl.ApiKey != ApiKey || cl.BaseUrl != "https://api.example.com/v.1.0/" || cl.userAgent != "acme-widgets/1.0" {
+		t.Log(cl.BaseUrl, cl.userAgent)
+		t.Fail()
+	}
+
+	if cl.httpClient == httpClient || cl.httpClient.Timeout != 5*time.Second || httpClient.Timeout != 0 {
+		t.Log("Timeout was not applied to a copy of the http client")
+		t.Fail()
+	}
+
+	if cl.retry.MaxAttempts != 3 || cl.rateLimiter == nil {
+		t.Log(cl.retry, cl.rateLimiter)
+		t.Fail()
+	}
+}
+
+func TestNewClientInvalidOptions(t *testing.T) {
+	if _, err := NewClient(""); err == nil {
+		t.Log("Missing api key was accepted")
+		t.Fail()
+	}
+
+	if _, err := NewClient(ApiKey, WithBaseURL("api.postageapp.com")); err == nil {
+		t.Log("Invalid base URL was accepted")
+		t.Fail()
+	}
+
+	if _, err := NewClient(ApiKey, WithRetry(0, time.Second, 0)); err == nil {
+		t.Log("Invalid retry policy was accepted")
+		t.Fail()
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	var mu sync.Mutex
+	userAgents := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents[r.Header.Get("User-Agent")]++
+		mu.Unlock()
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	defer server.Close()
+
+	cl, err := NewClient(ApiKey, WithBaseURL(server.URL), WithUserAgent("acme-widgets/1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cl.GetMessageReceipt("abc"); err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if userAgents["acme-widgets/1.0"] != 10 {
+		t.Log(userAgents)
+		t.Fail()
+	}
+}
@@ -0,0 +1,97 @@
+package postage_app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type Outbox struct {
+	MaxMessages int
+
+	mu       sync.Mutex
+	messages []*Message
+}
+
+var ErrOutboxFull = &PostageError{"outbox is full", nil}
+
+func NewOutbox(maxMessages int) *Outbox {
+	outbox := new(Outbox)
+	outbox.MaxMessages = maxMessages
+	return outbox
+}
+
+func (outbox *Outbox) Len() int {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	return len(outbox.messages)
+}
+
+func (outbox *Outbox) Enqueue(message *Message) error {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	if outbox.MaxMessages > 0 && len(outbox.messages) >= outbox.MaxMessages {
+		return ErrOutboxFull
+	}
+	outbox.messages = append(outbox.messages, message)
+	return nil
+}
+
+func (outbox *Outbox) enqueue(message *Message) (*MessageResponse, error) {
+	queued := *message
+	if queued.Uid == "" {
+		queued.Uid = newUid()
+	}
+	if err := outbox.Enqueue(&queued); err != nil {
+		return nil, err
+	}
+
+	response := new(MessageResponse)
+	response.Response = &Response{Status: "queued", Uid: queued.Uid}
+	response.Queued = true
+	return response, nil
+}
+
+func (outbox *Outbox) Flush(ctx context.Context, client *Client) (int, error) {
+	sent := 0
+	for {
+		outbox.mu.Lock()
+		if len(outbox.messages) == 0 {
+			outbox.mu.Unlock()
+			return sent, nil
+		}
+		message := outbox.messages[0]
+		outbox.messages = outbox.messages[1:]
+		outbox.mu.Unlock()
+
+		if _, err := client.sendMessage(ctx, message); err != nil {
+			var responseError *PostageResponseError
+			if errors.Is(err, ErrCircuitOpen) || errors.As(err, &responseError) {
+				outbox.mu.Lock()
+				outbox.messages = append([]*Message{message}, outbox.messages...)
+				outbox.mu.Unlock()
+			}
+			return sent, err
+		}
+		sent++
+	}
+}
+
+func (outbox *Outbox) Run(ctx context.Context, client *Client, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if client.circuitBreaker != nil && client.circuitBreaker.State() == BreakerOpen {
+			continue
+		}
+		if _, err := outbox.Flush(ctx, client); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package postage_app
 import (
 	"encoding/base64"
 	"encoding/json"
-	"time"
 )
 
 func (client *Client) ParseResponse(json map[string]interface{}) *Response {
@@ -107,12 +106,17 @@ func (client *Client) MarshalMessage(message *Message) ([]byte, error) {
 	return json.Marshal(hash)
 }
 
-func (client *Client) ParseMessages(json map[string]interface{}) map[string]*MessageInfo {
+func (client *Client) ParseMessages(json map[string]interface{}) (map[string]*MessageInfo, error) {
 	data := make(map[string]*MessageInfo)
 	for messageUid, messageInf := range json {
 		messageInfo := new(MessageInfo)
+		messageInfo.Uid = messageUid
 		miJ := messageInf.(map[string]interface{})
 
+		if miJ["id"] != nil {
+			messageInfo.Id = int(miJ["id"].(float64))
+		}
+
 		if miJ["project_id"] != nil {
 			messageInfo.ProjectId = int(miJ["project_id"].(float64))
 		}
@@ -133,20 +137,22 @@ func (client *Client) ParseMessages(json map[string]interface{}) map[string]*Mes
 			messageInfo.CompletedTransmissionsCount = int(miJ["transmissions_completed"].(float64))
 		}
 
-		if miJ["created_at"] != nil {
-			createdAt, _ := time.Parse(time.RFC3339, miJ["created_at"].(string))
-			messageInfo.CreatedAt = createdAt
+		createdAt, err := decodeTime(miJ, "created_at", "messages."+messageUid+".created_at")
+		if err != nil {
+			return nil, err
 		}
+		messageInfo.CreatedAt = createdAt.Time
 
-		if miJ["will_purge_at"] != nil {
-			willPurgeAt, _ := time.Parse(time.RFC3339, miJ["will_purge_at"].(string))
-			messageInfo.WillPurgeAt = willPurgeAt
+		willPurgeAt, err := decodeTime(miJ, "will_purge_at", "messages."+messageUid+".will_purge_at")
+		if err != nil {
+			return nil, err
 		}
+		messageInfo.WillPurgeAt = willPurgeAt.Time
 
 		data[messageUid] = messageInfo
 	}
 
-	return data
+	return data, nil
 }
 
 func (client *Client) ParseProjectInfo(json map[string]interface{}) *ProjectInfo {
@@ -200,7 +206,7 @@ func (client *Client) ParseMessageReceipt(json map[string]interface{}) *MessageR
 	return messageReceipt
 }
 
-func (client *Client) ParseMessageTransmissions(json map[string]interface{}) *MessageTransmissions {
+func (client *Client) ParseMessageTransmissions(json map[string]interface{}) (*MessageTransmissions, error) {
 	message := json["message"].(map[string]interface{})
 
 	messageTransmissions := new(MessageTransmissions)
@@ -209,7 +215,8 @@ func (client *Client) ParseMessageTransmissions(json map[string]interface{}) *Me
 	for email, tr := range json["transmissions"].(map[string]interface{}) {
 		transmissionJson := tr.(map[string]interface{})
 		messageTransmission := new(MessageTransmission)
-		messageTransmission.Status = transmissionJson["status"].(string)
+		status, _ := transmissionJson["status"].(string)
+		messageTransmission.Status = ParseTransmissionStatus(status)
 		if transmissionJson["error_message"] != nil {
 			messageTransmission.ResultMessage = transmissionJson["error_message"].(string)
 		}
@@ -217,72 +224,55 @@ func (client *Client) ParseMessageTransmissions(json map[string]interface{}) *Me
 			messageTransmission.ResultCode = transmissionJson["result_code"].(string)
 		}
 
-		if transmissionJson["created_at"] != nil {
-			createdAt, _ := time.Parse(time.RFC3339, transmissionJson["created_at"].(string))
-			messageTransmission.CreatedAt = createdAt
+		field := "transmissions." + maskEmails(email)
+		createdAt, err := decodeTime(transmissionJson, "created_at", field+".created_at")
+		if err != nil {
+			return nil, err
 		}
+		messageTransmission.CreatedAt = createdAt.Time
 
-		if transmissionJson["failed_at"] != nil {
-			failedAt, _ := time.Parse(time.RFC3339, transmissionJson["failed_at"].(string))
-			messageTransmission.FailedAt = failedAt
+		if messageTransmission.FailedAt, err = decodeTime(transmissionJson, "failed_at", field+".failed_at"); err != nil {
+			return nil, err
 		}
-
-		if transmissionJson["opened_at"] != nil {
-			openedAt, _ := time.Parse(time.RFC3339, transmissionJson["opened_at"].(string))
-			messageTransmission.OpenedAt = openedAt
+		if messageTransmission.OpenedAt, err = decodeTime(transmissionJson, "opened_at", field+".opened_at"); err != nil {
+			return nil, err
+		}
+		if messageTransmission.ClickedAt, err = decodeTime(transmissionJson, "clicked_at", field+".clicked_at"); err != nil {
+			return nil, err
 		}
 
 		messageTransmissions.Transmissions[email] = messageTransmission
 	}
 
-	return messageTransmissions
+	return messageTransmissions, nil
 }
 
 func (client *Client) ParseMetrics(json map[string]interface{}) *Metrics {
 	metrics := new(Metrics)
-	metricsJson := json["metrics"].(map[string]interface{})
+	metricsJson, _ := json["metrics"].(map[string]interface{})
 
 	for metricKey, metricJson := range metricsJson {
+		statisticsJson, ok := metricJson.(map[string]interface{})
+		if !ok {
+			continue
+		}
 		metric := new(Metric)
-		for metricStatisticKey, msj := range metricJson.(map[string]interface{}) {
-			metricStatisticJson := msj.(map[string]interface{})
-			metricStatistic := new(MetricStatistic)
-			metricStatistic.CurrentPercent = metricStatisticJson["current_percent"].(float64)
-			metricStatistic.PreviousPercent = metricStatisticJson["previous_percent"].(float64)
-			metricStatistic.DiffPercent = metricStatisticJson["diff_percent"].(float64)
-			metricStatistic.CurrentValue = int(metricStatisticJson["current_value"].(float64))
-			metricStatistic.PreviousValue = int(metricStatisticJson["previous_value"].(float64))
-
-			switch metricStatisticKey {
-			case "delivered":
-				metric.Delivered = metricStatistic
-			case "opened":
-				metric.Opened = metricStatistic
-			case "failed":
-				metric.Failed = metricStatistic
-			case "rejected":
-				metric.Rejected = metricStatistic
-			case "created":
-				metric.Created = metricStatistic
-			case "queued":
-				metric.Queued = metricStatistic
-			case "clicked":
-				metric.Clicked = metricStatistic
-			case "spammed":
-				metric.Spammed = metricStatistic
+		for metricStatisticKey, msj := range statisticsJson {
+			metricStatisticJson, ok := msj.(map[string]interface{})
+			if !ok {
+				continue
 			}
+			metricStatistic := new(MetricStatistic)
+			metricStatistic.CurrentPercent, metricStatistic.Valid.CurrentPercent = metricNumber(metricStatisticJson["current_percent"])
+			metricStatistic.PreviousPercent, metricStatistic.Valid.PreviousPercent = metricNumber(metricStatisticJson["previous_percent"])
+			metricStatistic.DiffPercent, metricStatistic.Valid.DiffPercent = metricNumber(metricStatisticJson["diff_percent"])
+			currentValue, valid := metricNumber(metricStatisticJson["current_value"])
+			metricStatistic.CurrentValue, metricStatistic.Valid.CurrentValue = int(currentValue), valid
+			previousValue, valid := metricNumber(metricStatisticJson["previous_value"])
+			metricStatistic.PreviousValue, metricStatistic.Valid.PreviousValue = int(previousValue), valid
+			metric.setStatistic(metricStatisticKey, metricStatistic)
 		}
-
-		switch metricKey {
-		case "hour":
-			metrics.Hour = metric
-		case "date":
-			metrics.Date = metric
-		case "week":
-			metrics.Week = metric
-		case "month":
-			metrics.Month = metric
-		}
+		metrics.setPeriod(metricKey, metric)
 	}
 
 	return metrics
@@ -154,7 +154,10 @@ func TestMessageParseIncludesAttachments(t *testing.T) {
 func TestMessageTransmissionsParse(t *testing.T) {
 	cl, _ := InitMessage()
 	js := unmarshal(`{"message":{"id":34968902},"transmissions":{"test@null.postageapp.com":{"status":"completed","created_at":"2013-03-21 17:13:21","failed_at":null,"opened_at":null,"clicked_at":null,"result_code":"SMTP_250","error_message":"2.0.0 OK 1363886006 jt2si6390208obb.44 - gsmtp"}}}`)
-	mT := cl.ParseMessageTransmissions(js)
+	mT, err := cl.ParseMessageTransmissions(js)
+	if err != nil {
+		t.Fatal(err)
+	}
 	if mT.Id != 34968902 {
 		t.Log(mT.Id)
 		t.Fail()
@@ -171,8 +174,8 @@ func TestMessageTransmissionsParse(t *testing.T) {
 			t.Fail()
 		}
 
-		ti, _ := time.Parse(time.RFC3339, "2013-03-21 17:13:21")
-		if v.CreatedAt != ti {
+		ti := time.Date(2013, 3, 21, 17, 13, 21, 0, time.UTC)
+		if !v.CreatedAt.Equal(ti) || v.FailedAt.Valid || v.OpenedAt.Valid {
 			t.Log(v.CreatedAt)
 			t.Fail()
 		}
@@ -347,3 +350,108 @@ func TestProjectInfoConverter(t *testing.T) {
 		break
 	}
 }
+
+func TestMetricsParseNullsAndUnknownKeys(t *testing.T) {
+	cl, _ := InitMessage()
+	js := unmarshal(`{"metrics":{"hour":{"delivered":{"current_percent":null,"previous_percent":"12.5","diff_percent":null,"current_value":4,"previous_value":null},"bounced":{"current_percent":1.5,"previous_percent":0,"diff_percent":1.5,"current_value":2,"previous_value":0},"broken":"n/a"},"quarter":{"failed":{"current_percent":3,"previous_percent":1,"diff_percent":2,"current_value":3,"previous_value":1}},"year":null}}`)
+	mT := cl.ParseMetrics(js)
+
+	delivered := mT.Hour.Delivered
+	if delivered == nil || delivered.Valid.CurrentPercent || delivered.Valid.DiffPercent || delivered.Valid.PreviousValue ||
+		!delivered.Valid.CurrentValue || delivered.CurrentValue != 4 || !delivered.Valid.PreviousPercent || delivered.PreviousPercent != 12.5 {
+		t.Log(delivered)
+		t.Fail()
+	}
+	if bounced := mT.Hour.Extra["bounced"]; bounced == nil || bounced.CurrentValue != 2 {
+		t.Log(mT.Hour.Extra)
+		t.Fail()
+	}
+	if quarter := mT.Extra["quarter"]; quarter == nil || quarter.Failed == nil || quarter.Failed.CurrentPercent != 3 {
+		t.Log(mT.Extra)
+		t.Fail()
+	}
+
+	if periods := fmt.Sprint(mT.Periods()); periods != "[hour quarter]" {
+		t.Log(periods)
+		t.Fail()
+	}
+	var names []string
+	mT.Hour.Each(func(name string, s *MetricStatistic) {
+		names = append(names, name)
+	})
+	if fmt.Sprint(names) != "[delivered bounced]" {
+		t.Log(names)
+		t.Fail()
+	}
+	if mT.Period("quarter").Statistic("failed") != mT.Extra["quarter"].Failed || mT.Period("week") != nil {
+		t.Fail()
+	}
+}
+
+func TestParseTimeFormats(t *testing.T) {
+	expected := time.Date(2013, 3, 21, 17, 13, 21, 0, time.UTC)
+	for _, value := range []string{
+		"2013-03-21T17:13:21Z",
+		"2013-03-21T17:13:21.000Z",
+		"2013-03-21T13:13:21-04:00",
+		"2013-03-21T13:13:21-0400",
+		"2013-03-21 17:13:21",
+		"2013-03-21 17:13:21.000",
+		"2013-03-21 13:13:21 -0400",
+		"2013-03-21 17:13:21 UTC",
+		"2013-03-21 17:13:21 GMT",
+		"2013-03-21 18:13:21+01:00",
+	} {
+		parsed, err := ParseTime(value)
+		if err != nil || !parsed.Equal(expected) {
+			t.Log(value, parsed, err)
+			t.Fail()
+		}
+	}
+	for _, value := range []string{"21/03/2013", "2013-03-21 12:13:21 EST", "2013-03-21 10:13:21 PDT"} {
+		if parsed, err := ParseTime(value); err == nil {
+			t.Log(value, parsed)
+			t.Fail()
+		}
+	}
+}
+
+func TestMessagesParseReportsBadTimestamps(t *testing.T) {
+	cl, _ := InitMessage()
+	js := unmarshal(`{"abc":{"created_at":"yesterday","will_purge_at":null}}`)
+	if _, err := cl.ParseMessages(js); err == nil || err.Error() != `messages.abc.created_at: cannot parse time "yesterday"` {
+		t.Log(err)
+		t.Fail()
+	}
+
+	js = unmarshal(`{"message":{"id":1},"transmissions":{"jane@example.com":{"status":"failed","created_at":"2013-03-21 17:13:21","failed_at":12}}}`)
+	if _, err := cl.ParseMessageTransmissions(js); err == nil || strings.Contains(err.Error(), "jane@") {
+		t.Log(err)
+		t.Fail()
+	}
+
+	js = unmarshal(`{"abc":{"created_at":"2013-03-21 17:13:21"}}`)
+	messages, err := cl.ParseMessages(js)
+	if err != nil || messages["abc"].CreatedAt.Year() != 2013 || !messages["abc"].WillPurgeAt.IsZero() {
+		t.Log(messages, err)
+		t.Fail()
+	}
+}
+
+func TestNullTimeJSON(t *testing.T) {
+	var value struct {
+		At NullTime
+	}
+	if err := json.Unmarshal([]byte(`{"At":"2013-03-21 17:13:21"}`), &value); err != nil || !value.At.Valid {
+		t.Log(value, err)
+		t.Fail()
+	}
+	b, _ := json.Marshal(value)
+	if string(b) != `{"At":"2013-03-21T17:13:21Z"}` {
+		t.Log(string(b))
+		t.Fail()
+	}
+	if err := json.Unmarshal([]byte(`{"At":null}`), &value); err != nil || value.At.Valid {
+		t.Fail()
+	}
+}
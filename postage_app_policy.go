@@ -0,0 +1,94 @@
+package postage_app
+
+import (
+	"net/mail"
+	"strings"
+)
+
+type RecipientPolicy struct {
+	Override    string
+	Allow       []string
+	BlockOthers bool
+}
+
+type PolicyReport struct {
+	Allowed    []string
+	Overridden []string
+	Blocked    []string
+}
+
+type PolicyError PostageError
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+func (policy *RecipientPolicy) Allowed(email string) bool {
+	address := email
+	if parsed, err := mail.ParseAddress(email); err == nil {
+		address = parsed.Address
+	}
+	address = strings.ToLower(address)
+	domain := address[strings.LastIndex(address, "@")+1:]
+
+	for _, allowed := range policy.Allow {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if strings.Contains(allowed, "@") && !strings.HasPrefix(allowed, "@") {
+			if address == allowed {
+				return true
+			}
+			continue
+		}
+		allowed = strings.TrimPrefix(allowed, "@")
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy *RecipientPolicy) Apply(message *Message) (*Message, *PolicyReport, error) {
+	report := new(PolicyReport)
+	applied := *message
+	applied.Recipients = nil
+
+	block := policy.BlockOthers || policy.Override == ""
+	var others []*Recipient
+	for _, recipient := range message.Recipients {
+		if policy.Allowed(recipient.Email) {
+			report.Allowed = append(report.Allowed, recipient.Email)
+			applied.Recipients = append(applied.Recipients, recipient)
+			continue
+		}
+		others = append(others, recipient)
+	}
+
+	redirect := !block && len(applied.Recipients) == 0
+	for _, recipient := range others {
+		if redirect {
+			report.Overridden = append(report.Overridden, recipient.Email)
+			applied.Recipients = append(applied.Recipients, recipient)
+		} else {
+			report.Blocked = append(report.Blocked, recipient.Email)
+		}
+	}
+
+	if message.RecipientOverride != "" && !policy.Allowed(message.RecipientOverride) {
+		if block {
+			report.Blocked = append(report.Blocked, message.RecipientOverride)
+			applied.RecipientOverride = ""
+		} else {
+			report.Overridden = append(report.Overridden, message.RecipientOverride)
+			applied.RecipientOverride = policy.Override
+		}
+	}
+
+	if redirect && len(others) != 0 && applied.RecipientOverride == "" {
+		applied.RecipientOverride = policy.Override
+	}
+
+	if len(message.Recipients) != 0 && len(applied.Recipients) == 0 {
+		return nil, report, &PolicyError{"recipient_policy: all recipients blocked: " + strings.Join(report.Blocked, ", "), nil}
+	}
+	return &applied, report, nil
+}
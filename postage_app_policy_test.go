@@ -0,0 +1,158 @@
+package postage_app
+
+import (
+	"strings"
+	"testing"
+)
+
+func InitPolicyMessage() *Message {
+	message := new(Message)
+	message.Uid = "6e36017c-b662-441b-92cb-3acba3d556f4"
+	message.Text = "This is my text content"
+	for _, email := range []string{"Alan Smithee <alan.smithee@gmail.com>", "dev@acme.com", "qa@staging.acme.com", "rick.james@gmail.com"} {
+		recipient := new(Recipient)
+		recipient.Email = email
+		recipient.Variables = map[string]string{"email": email}
+		message.Recipients = append(message.Recipients, recipient)
+	}
+	return message
+}
+
+func TestRecipientPolicyAllowed(t *testing.T) {
+	policy := &RecipientPolicy{Allow: []string{"acme.com", "Rick.James@gmail.com"}}
+
+	for email, expected := range map[string]bool{
+		"dev@acme.com":                        true,
+		"QA <qa@staging.ACME.com>":            true,
+		"rick.james@gmail.com":                true,
+		"alan.smithee@gmail.com":              false,
+		"someone@notacme.com":                 false,
+		"Rick James <rick.james@example.com>": false,
+	} {
+		if policy.Allowed(email) != expected {
+			t.Log(email)
+			t.Fail()
+		}
+	}
+}
+
+func TestRecipientPolicyOverride(t *testing.T) {
+	policy := &RecipientPolicy{Override: "dev@acme.com", Allow: []string{"acme.com"}}
+	message := InitPolicyMessage()
+
+	applied, report, err := policy.Apply(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(message.Recipients) != 4 {
+		t.Log("Original message was modified")
+		t.Fail()
+	}
+
+	emails := []string{}
+	for _, recipient := range applied.Recipients {
+		emails = append(emails, recipient.Email)
+	}
+	if strings.Join(emails, ",") != "dev@acme.com,qa@staging.acme.com" || applied.RecipientOverride != "" {
+		t.Log(emails, applied.RecipientOverride)
+		t.Fail()
+	}
+	if applied.Recipients[0].Variables["email"] != "dev@acme.com" {
+		t.Log("allowed recipient lost its variables", applied.Recipients[0].Variables)
+		t.Fail()
+	}
+
+	if len(report.Allowed) != 2 || len(report.Overridden) != 0 || len(report.Blocked) != 2 {
+		t.Log(report)
+		t.Fail()
+	}
+}
+
+func TestRecipientPolicyOverridesEveryRecipient(t *testing.T) {
+	policy := &RecipientPolicy{Override: "dev@acme.com", Allow: []string{"example.com"}}
+	message := InitPolicyMessage()
+
+	applied, report, err := policy.Apply(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied.RecipientOverride != "dev@acme.com" || len(applied.Recipients) != 4 || len(report.Overridden) != 4 {
+		t.Log(applied.RecipientOverride, applied.Recipients, report)
+		t.Fail()
+	}
+	for _, recipient := range applied.Recipients {
+		if recipient.Variables["email"] != recipient.Email {
+			t.Log("recipient lost its variables", recipient.Email, recipient.Variables)
+			t.Fail()
+		}
+	}
+}
+
+func TestRecipientPolicyBlock(t *testing.T) {
+	policy := &RecipientPolicy{Allow: []string{"acme.com"}, BlockOthers: true}
+	message := InitPolicyMessage()
+	message.RecipientOverride = "someone@gmail.com"
+
+	applied, report, err := policy.Apply(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied.Recipients) != 2 || applied.RecipientOverride != "" {
+		t.Log(applied.Recipients, applied.RecipientOverride)
+		t.Fail()
+	}
+
+	if len(report.Blocked) != 3 {
+		t.Log(report.Blocked)
+		t.Fail()
+	}
+}
+
+func TestRecipientPolicyBlocksEverything(t *testing.T) {
+	policy := &RecipientPolicy{Allow: []string{"example.com"}}
+	_, report, err := policy.Apply(InitPolicyMessage())
+
+	if _, ok := err.(*PolicyError); !ok {
+		t.Log(err)
+		t.Fail()
+	}
+
+	if len(report.Blocked) != 4 {
+		t.Log(report.Blocked)
+		t.Fail()
+	}
+}
+
+func TestSendMessageAppliesRecipientPolicy(t *testing.T) {
+	sink := new(MemorySink)
+	cl := new(Client)
+	cl.ApiKey = ApiKey
+	cl.sandbox = NewSandbox(sink)
+	cl.recipientPolicy = &RecipientPolicy{Override: "inbox@acme.com", Allow: []string{"acme.com"}}
+
+	response, err := cl.SendMessage(InitPolicyMessage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Policy == nil || len(response.Policy.Overridden) != 0 || len(response.Policy.Blocked) != 2 {
+		t.Log(response.Policy)
+		t.Fail()
+	}
+
+	payload := unmarshal(string(sink.Records()[0].Payload))
+	recipients := payload["arguments"].(map[string]interface{})["recipients"].(map[string]interface{})
+	for email := range recipients {
+		if !strings.HasSuffix(email, "acme.com") {
+			t.Log(email)
+			t.Fail()
+		}
+	}
+
+	if len(recipients) != 2 {
+		t.Log(recipients)
+		t.Fail()
+	}
+}
@@ -0,0 +1,59 @@
+package postage_app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var apiKeyField = regexp.MustCompile(`("api_key"\s*:\s*")((?:[^"\\]|\\.)*)(")`)
+
+func (client *Client) String() string {
+	return fmt.Sprintf("Client{ApiKey:%q BaseUrl:%q Timeout:%v Sandbox:%t}",
+		maskApiKey(client.ApiKey), client.BaseUrl, client.timeout, client.sandbox != nil)
+}
+
+func (client *Client) GoString() string {
+	return client.String()
+}
+
+func RedactPayload(payload []byte) []byte {
+	return apiKeyField.ReplaceAllFunc(payload, func(field []byte) []byte {
+		parts := apiKeyField.FindSubmatch(field)
+		return []byte(string(parts[1]) + maskApiKey(string(parts[2])) + string(parts[3]))
+	})
+}
+
+func redact(s string, keys ...string) string {
+	for _, key := range keys {
+		if key != "" {
+			s = strings.ReplaceAll(s, key, maskApiKey(key))
+		}
+	}
+	return s
+}
+
+func redactError(err error, keys ...string) error {
+	if err == nil {
+		return nil
+	}
+	message := redact(err.Error(), keys...)
+	if message == err.Error() {
+		return err
+	}
+	switch err.(type) {
+	case *PostageResponseError:
+		return &PostageResponseError{message, nil}
+	case *ResponseParseError:
+		return &ResponseParseError{message, nil}
+	}
+	return &PostageError{message, nil}
+}
+
+func payloadApiKey(payload []byte) string {
+	parts := apiKeyField.FindSubmatch(payload)
+	if parts == nil {
+		return ""
+	}
+	return string(parts[2])
+}
@@ -0,0 +1,122 @@
+package postage_app
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const redactTestKey = "s3cr3t-api-key-0123456789abcdef"
+
+func assertRedacted(t *testing.T, label string, values ...interface{}) {
+	for _, value := range values {
+		for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+			if out := fmt.Sprintf(format, value); strings.Contains(out, redactTestKey) {
+				t.Logf("%s: %s leaked the api key: %s", label, format, out)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestClientStringWhileAddingMiddleware(t *testing.T) {
+	cl, _ := NewClient(redactTestKey)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			cl.Use(func(next Handler) Handler { return next })
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = cl.String()
+	}
+	<-done
+}
+
+func TestRedactClient(t *testing.T) {
+	cl := new(Client)
+	cl.ApiKey = redactTestKey
+	assertRedacted(t, "client", cl)
+
+	if !strings.Contains(cl.String(), "****cdef") {
+		t.Log(cl.String())
+		t.Fail()
+	}
+}
+
+func TestRedactPayload(t *testing.T) {
+	payload := []byte(`{"api_key":"` + redactTestKey + `", "uid":"abc"}`)
+	redacted := string(RedactPayload(payload))
+	if redacted != `{"api_key":"****cdef", "uid":"abc"}` {
+		t.Log(redacted)
+		t.Fail()
+	}
+	if payloadApiKey(payload) != redactTestKey {
+		t.Fail()
+	}
+}
+
+func TestRedactErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html/get_project_info.json":
+			w.Write([]byte("<html>" + redactTestKey + "</html>"))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logged, nil))
+
+	for _, baseUrl := range []string{
+		"http://127.0.0.1:1/" + redactTestKey + "/",
+		server.URL + "/html/",
+		server.URL + "/" + redactTestKey + "/",
+	} {
+		cl, err := NewClient(redactTestKey, WithBaseURL(baseUrl), WithLogger(logger), WithRetry(2, time.Millisecond, time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = cl.GetProjectInfo()
+		if err == nil {
+			t.Log(baseUrl)
+			t.Fail()
+			continue
+		}
+		assertRedacted(t, baseUrl, err)
+
+		_, _, message := InitSandboxMessage()
+		_, err = cl.SendMessage(message)
+		assertRedacted(t, baseUrl, err)
+	}
+
+	if strings.Contains(logged.String(), redactTestKey) {
+		t.Log(logged.String())
+		t.Fail()
+	}
+}
+
+func TestRedactSandboxRecord(t *testing.T) {
+	sink := new(MemorySink)
+	cl, _ := NewClient(redactTestKey, WithSandbox(NewSandbox(sink)))
+	_, _, message := InitSandboxMessage()
+	if _, err := cl.SendMessage(message); err != nil {
+		t.Fatal(err)
+	}
+	records := sink.Records()
+	if len(records) != 1 {
+		t.Fatal(records)
+	}
+	if strings.Contains(string(records[0].Payload), redactTestKey) || !strings.Contains(string(records[0].Payload), "****cdef") {
+		t.Log(string(records[0].Payload))
+		t.Fail()
+	}
+}
@@ -0,0 +1,255 @@
+package postage_app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type projectKey struct{}
+
+func WithProject(ctx context.Context, project string) context.Context {
+	return context.WithValue(ctx, projectKey{}, project)
+}
+
+func ProjectFromContext(ctx context.Context) string {
+	project, _ := ctx.Value(projectKey{}).(string)
+	return project
+}
+
+type Registry struct {
+	Default string
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+type RegistryError struct {
+	Message string
+	Errors  map[string]error
+}
+
+func (e *RegistryError) Error() string {
+	if len(e.Errors) == 0 {
+		return e.Message
+	}
+	names := make([]string, 0, len(e.Errors))
+	for name, err := range e.Errors {
+		names = append(names, name+": "+err.Error())
+	}
+	sort.Strings(names)
+	return e.Message + ": " + strings.Join(names, "; ")
+}
+
+func NewRegistry() *Registry {
+	registry := new(Registry)
+	registry.clients = make(map[string]*Client)
+	return registry
+}
+
+func (registry *Registry) Register(name string, client *Client) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if registry.clients == nil {
+		registry.clients = make(map[string]*Client)
+	}
+	registry.clients[name] = client
+}
+
+func (registry *Registry) Remove(name string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	delete(registry.clients, name)
+}
+
+func (registry *Registry) Replace(clients map[string]*Client) {
+	replacement := make(map[string]*Client, len(clients))
+	for name, client := range clients {
+		replacement[name] = client
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	registry.clients = replacement
+}
+
+func (registry *Registry) Client(name string) (*Client, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	client, ok := registry.clients[name]
+	return client, ok
+}
+
+func (registry *Registry) Names() []string {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	names := make([]string, 0, len(registry.clients))
+	for name := range registry.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (registry *Registry) Resolve(ctx context.Context, message *Message) (*Client, error) {
+	name := ""
+	if message != nil {
+		name = message.Project
+	}
+	if name == "" && ctx != nil {
+		name = ProjectFromContext(ctx)
+	}
+	if name == "" {
+		name = registry.Default
+	}
+	if name == "" {
+		return nil, &RegistryError{"registry: no project given and no default project", nil}
+	}
+	client, ok := registry.Client(name)
+	if !ok {
+		return nil, &RegistryError{"registry: unknown project " + name, nil}
+	}
+	return client, nil
+}
+
+func (registry *Registry) SendMessage(ctx context.Context, message *Message) (*MessageResponse, error) {
+	client, err := registry.Resolve(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return client.SendMessageContext(ctx, message)
+}
+
+func (registry *Registry) each(call func(client *Client) (interface{}, error)) (map[string]interface{}, error) {
+	registry.mu.RLock()
+	clients := make(map[string]*Client, len(registry.clients))
+	for name, client := range registry.clients {
+		clients[name] = client
+	}
+	registry.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]interface{})
+	errors := make(map[string]error)
+	for name, client := range clients {
+		wg.Add(1)
+		go func(name string, client *Client) {
+			defer wg.Done()
+			result, err := call(client)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errors[name] = err
+			} else {
+				results[name] = result
+			}
+		}(name, client)
+	}
+	wg.Wait()
+
+	if len(errors) != 0 {
+		return results, &RegistryError{"registry: some projects failed", errors}
+	}
+	return results, nil
+}
+
+func (registry *Registry) GetProjectInfo() (map[string]*ProjectResponse, error) {
+	results, err := registry.each(func(client *Client) (interface{}, error) {
+		return client.GetProjectInfo()
+	})
+	responses := make(map[string]*ProjectResponse, len(results))
+	for name, result := range results {
+		responses[name] = result.(*ProjectResponse)
+	}
+	return responses, err
+}
+
+func (registry *Registry) GetMetrics() (map[string]*MetricsResponse, error) {
+	results, err := registry.each(func(client *Client) (interface{}, error) {
+		return client.GetMetrics()
+	})
+	responses := make(map[string]*MetricsResponse, len(results))
+	for name, result := range results {
+		responses[name] = result.(*MetricsResponse)
+	}
+	return responses, err
+}
+
+func SumTransmissions(responses map[string]*ProjectResponse) *TransmissionsStatistic {
+	total := new(TransmissionsStatistic)
+	for _, response := range responses {
+		if response == nil || response.Data == nil || response.Data.Transmissions == nil {
+			continue
+		}
+		total.TodayCount += response.Data.Transmissions.TodayCount
+		total.ThisMonthCount += response.Data.Transmissions.ThisMonthCount
+		total.OverallCount += response.Data.Transmissions.OverallCount
+	}
+	return total
+}
+
+func LoadRegistryClients(path string) (map[string]*Client, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, &ConfigError{"file", path, err.Error()}
+	}
+
+	var projects map[string]json.RawMessage
+	if err := json.Unmarshal(content, &projects); err != nil {
+		return nil, &ConfigError{"file", path, err.Error()}
+	}
+
+	clients := make(map[string]*Client, len(projects))
+	for name, raw := range projects {
+		config := new(Config)
+		if err := config.loadJson(raw, path+"#"+name); err != nil {
+			return nil, err
+		}
+		client, err := config.NewClient()
+		if err != nil {
+			if configError, ok := err.(*ConfigError); ok && configError.Source == configEnvName(configError.Setting) {
+				configError.Source = path + "#" + name
+			}
+			return nil, err
+		}
+		clients[name] = client
+	}
+	return clients, nil
+}
+
+func (registry *Registry) ReloadFile(path string) error {
+	clients, err := LoadRegistryClients(path)
+	if err != nil {
+		return err
+	}
+	registry.Replace(clients)
+	return nil
+}
+
+func (registry *Registry) WatchFile(ctx context.Context, path string, interval time.Duration, onError func(error)) {
+	var last []byte
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		content, err := os.ReadFile(path)
+		if err == nil && !bytes.Equal(content, last) {
+			if err = registry.ReloadFile(path); err == nil {
+				last = content
+			}
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package postage_app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func InitRegistry() (*Registry, *MemorySink, *MemorySink) {
+	acme := new(MemorySink)
+	widgets := new(MemorySink)
+	registry := NewRegistry()
+	acmeClient, _ := NewClient("acme-key", WithSandbox(NewSandbox(acme)))
+	widgetsClient, _ := NewClient("widgets-key", WithSandbox(NewSandbox(widgets)))
+	registry.Register("acme", acmeClient)
+	registry.Register("widgets", widgetsClient)
+	return registry, acme, widgets
+}
+
+func TestRegistryRoutesSendMessage(t *testing.T) {
+	registry, acme, widgets := InitRegistry()
+	_, _, message := InitSandboxMessage()
+
+	if _, err := registry.SendMessage(WithProject(context.Background(), "widgets"), message); err != nil {
+		t.Fatal(err)
+	}
+
+	message2 := *message
+	message2.Uid = "order-556"
+	message2.Project = "acme"
+	if _, err := registry.SendMessage(WithProject(context.Background(), "widgets"), &message2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(acme.Records()) != 1 || len(widgets.Records()) != 1 {
+		t.Log(len(acme.Records()), len(widgets.Records()))
+		t.Fail()
+	}
+
+	_, err := registry.SendMessage(context.Background(), message)
+	if _, ok := err.(*RegistryError); !ok {
+		t.Log(err)
+		t.Fail()
+	}
+
+	registry.Default = "acme"
+	if _, err := registry.SendMessage(context.Background(), message); err != nil || len(acme.Records()) != 2 {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRegistryAggregates(t *testing.T) {
+	registry, _, _ := InitRegistry()
+	_, _, message := InitSandboxMessage()
+	registry.SendMessage(WithProject(context.Background(), "acme"), message)
+	registry.SendMessage(WithProject(context.Background(), "widgets"), message)
+
+	projects, err := registry.GetProjectInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(projects) != 2 || SumTransmissions(projects).OverallCount != 2 {
+		t.Log(projects)
+		t.Fail()
+	}
+
+	metrics, err := registry.GetMetrics()
+	if err != nil || metrics["acme"].Data.Hour.Delivered.CurrentValue != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRegistryReloadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+	os.WriteFile(path, []byte(`{"acme": {"api_key": "acme-key"}, "widgets": {"api_key": "widgets-key", "timeout": "5s"}}`), 0600)
+
+	registry := NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go registry.WatchFile(ctx, path, 10*time.Millisecond, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for len(registry.Names()) != 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	widgets, ok := registry.Client("widgets")
+	if !ok || widgets.ApiKey != "widgets-key" || widgets.timeout != 5*time.Second {
+		t.Log(registry.Names())
+		t.Fail()
+	}
+
+	os.WriteFile(path, []byte(`{"acme": {"api_key": "rotated-key"}}`), 0600)
+	deadline = time.Now().Add(time.Second)
+	for len(registry.Names()) != 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	acme, _ := registry.Client("acme")
+	if len(registry.Names()) != 1 || acme.ApiKey != "rotated-key" {
+		t.Log(registry.Names())
+		t.Fail()
+	}
+
+	err := registry.ReloadFile(filepath.Join(t.TempDir(), "missing.json"))
+	if err == nil || len(registry.Names()) != 1 {
+		t.Log("Failed reload replaced the key set")
+		t.Fail()
+	}
+}
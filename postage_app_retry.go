@@ -0,0 +1,106 @@
+package postage_app
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+func (policy *RetryPolicy) attempts() int {
+	if policy == nil || policy.MaxAttempts < 1 {
+		return 1
+	}
+	return policy.MaxAttempts
+}
+
+func (policy *RetryPolicy) delay(attempt int) time.Duration {
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = 500 * time.Millisecond
+	}
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			return policy.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewRateLimiter(perSecond float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := new(RateLimiter)
+	limiter.rate = perSecond
+	limiter.burst = float64(burst)
+	limiter.tokens = float64(burst)
+	return limiter
+}
+
+func (limiter *RateLimiter) reserve() time.Duration {
+	if limiter.rate <= 0 {
+		return 0
+	}
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := time.Now()
+	if !limiter.last.IsZero() {
+		limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.rate
+		if limiter.tokens > limiter.burst {
+			limiter.tokens = limiter.burst
+		}
+	}
+	limiter.last = now
+	limiter.tokens--
+	if limiter.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-limiter.tokens / limiter.rate * float64(time.Second))
+}
+
+func (limiter *RateLimiter) Wait() time.Duration {
+	wait, _ := limiter.WaitContext(context.Background())
+	return wait
+}
+
+func (limiter *RateLimiter) WaitContext(ctx context.Context) (time.Duration, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	wait := limiter.reserve()
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return wait, nil
+	case <-ctx.Done():
+		limiter.cancel()
+		return wait, ctx.Err()
+	}
+}
+
+func (limiter *RateLimiter) cancel() {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+	limiter.tokens++
+}
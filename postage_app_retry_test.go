@@ -0,0 +1,107 @@
+package postage_app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryOnServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"response":{"status":"ok","uid":"abc"},"data":{"message":{"id":1,"url":"https://api.postageapp.com/receipt/1"}}}`)
+	}))
+	defer server.Close()
+
+	cl := new(Client)
+	cl.ApiKey = ApiKey
+	cl.BaseUrl = server.URL + "/"
+	cl.retry = &RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+
+	response, err := cl.GetMessageReceipt("abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Data.Id != 1 || atomic.LoadInt32(&calls) != 2 {
+		t.Log(response.Data.Id, calls)
+		t.Fail()
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cl := new(Client)
+	cl.ApiKey = ApiKey
+	cl.BaseUrl = server.URL + "/"
+	cl.retry = &RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}
+
+	_, err := cl.GetMessageReceipt("abc")
+	if _, ok := err.(*PostageResponseError); !ok {
+		t.Log(err)
+		t.Fail()
+	}
+
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Log(calls)
+		t.Fail()
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	limiter := NewRateLimiter(100, 2)
+	if limiter.Wait() != 0 || limiter.Wait() != 0 {
+		t.Log("Burst was not honoured")
+		t.Fail()
+	}
+
+	if wait := limiter.Wait(); wait <= 0 || wait > 20*time.Millisecond {
+		t.Log(wait)
+		t.Fail()
+	}
+}
+
+func TestRateLimiterHonoursContext(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	defer server.Close()
+
+	cl, _ := NewClient(ApiKey, WithBaseURL(server.URL), WithRateLimit(0.01, 1))
+	if _, err := cl.GetMessageReceipt("abc"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := cl.GetMessageReceiptContext(ctx, "abc")
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second || atomic.LoadInt32(&calls) != 1 {
+		t.Log(err, time.Since(start), calls)
+		t.Fail()
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if wait, err := cl.rateLimiter.WaitContext(ctx); wait != 0 || !errors.Is(err, context.Canceled) {
+		t.Log(wait, err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,342 @@
+package postage_app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type SandboxRecord struct {
+	Id        int
+	Uid       string
+	Message   *Message
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+type Sink interface {
+	Record(record *SandboxRecord) error
+}
+
+type SinkFunc func(record *SandboxRecord) error
+
+func (f SinkFunc) Record(record *SandboxRecord) error {
+	return f(record)
+}
+
+type MemorySink struct {
+	mu      sync.Mutex
+	records []*SandboxRecord
+}
+
+func (sink *MemorySink) Record(record *SandboxRecord) error {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.records = append(sink.records, record)
+	return nil
+}
+
+func (sink *MemorySink) Records() []*SandboxRecord {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	return append([]*SandboxRecord(nil), sink.records...)
+}
+
+func (sink *MemorySink) Reset() {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.records = nil
+}
+
+type DirectorySink struct {
+	Dir  string
+	Json bool
+	Eml  bool
+}
+
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func NewDirectorySink(dir string) *DirectorySink {
+	sink := new(DirectorySink)
+	sink.Dir = dir
+	sink.Json = true
+	sink.Eml = true
+	return sink
+}
+
+func (sink *DirectorySink) base(record *SandboxRecord) (string, error) {
+	name := strings.TrimLeft(unsafeFileName.ReplaceAllString(record.Uid, "_"), ".")
+	if name == "" {
+		name = fmt.Sprintf("message-%d", record.Id)
+	}
+	base := filepath.Join(sink.Dir, name)
+	if filepath.Dir(base) != filepath.Clean(sink.Dir) {
+		return "", &PostageError{"sandbox: uid " + strconv.Quote(record.Uid) + " escapes the sink directory", nil}
+	}
+	return base, nil
+}
+
+func (sink *DirectorySink) Record(record *SandboxRecord) error {
+	if err := os.MkdirAll(sink.Dir, 0755); err != nil {
+		return err
+	}
+	base, err := sink.base(record)
+	if err != nil {
+		return err
+	}
+
+	if sink.Json {
+		if err := os.WriteFile(base+".json", record.Payload, 0644); err != nil {
+			return err
+		}
+	}
+
+	if sink.Eml {
+		message := record.Message.withDate(record.CreatedAt)
+		recipients := message.Recipients
+		if len(recipients) == 0 {
+			recipients = []*Recipient{nil}
+		}
+		for i, recipient := range recipients {
+			name := base + ".eml"
+			if len(recipients) > 1 {
+				name = fmt.Sprintf("%s-%d.eml", base, i+1)
+			}
+			file, err := os.Create(name)
+			if err != nil {
+				return err
+			}
+			err = message.WriteMIME(file, recipient)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+const DefaultSandboxMaxMessages = 1000
+
+type sandboxEntry struct {
+	id         int
+	uid        string
+	template   string
+	recipients []string
+	createdAt  time.Time
+}
+
+type Sandbox struct {
+	Sink        Sink
+	MaxMessages int
+
+	mu            sync.Mutex
+	lastId        int
+	transmissions int
+	entries       map[string]*sandboxEntry
+	order         []string
+}
+
+func NewSandbox(sink Sink) *Sandbox {
+	sandbox := new(Sandbox)
+	sandbox.Sink = sink
+	sandbox.MaxMessages = DefaultSandboxMaxMessages
+	return sandbox
+}
+
+func (sandbox *Sandbox) remember(entry *sandboxEntry) {
+	if sandbox.entries == nil {
+		sandbox.entries = make(map[string]*sandboxEntry)
+	}
+	sandbox.entries[entry.uid] = entry
+	sandbox.order = append(sandbox.order, entry.uid)
+
+	limit := sandbox.MaxMessages
+	if limit <= 0 {
+		limit = DefaultSandboxMaxMessages
+	}
+	for len(sandbox.order) > limit {
+		delete(sandbox.entries, sandbox.order[0])
+		sandbox.order = sandbox.order[1:]
+	}
+}
+
+func (sandbox *Sandbox) sendMessage(message *Message, payload []byte) (map[string]interface{}, error) {
+	if len(message.Recipients) == 0 && message.RecipientOverride == "" {
+		return sandboxStatus("bad_request", message.Uid), nil
+	}
+	if message.Template == "" && message.Text == "" && message.Html == "" {
+		return sandboxStatus("bad_request", message.Uid), nil
+	}
+
+	sandbox.mu.Lock()
+	defer sandbox.mu.Unlock()
+
+	uid := message.Uid
+	if uid == "" {
+		uid = newUid()
+	}
+
+	entry, ok := sandbox.entries[uid]
+	if !ok {
+		record := new(SandboxRecord)
+		record.Id = sandbox.lastId + 1
+		record.Uid = uid
+		record.Message = message
+		record.Payload = RedactPayload(payload)
+		record.CreatedAt = time.Now().UTC()
+
+		if sandbox.Sink != nil {
+			if err := sandbox.Sink.Record(record); err != nil {
+				return nil, &PostageResponseError{"sandbox: " + err.Error(), err}
+			}
+		}
+		sandbox.lastId = record.Id
+
+		entry = &sandboxEntry{id: record.Id, uid: uid, template: message.Template, createdAt: record.CreatedAt}
+		for _, recipient := range message.Recipients {
+			entry.recipients = append(entry.recipients, recipient.Email)
+		}
+		sandbox.transmissions += len(entry.recipients)
+		sandbox.remember(entry)
+	}
+
+	return map[string]interface{}{
+		"response": map[string]interface{}{"status": "ok", "uid": uid},
+		"data":     map[string]interface{}{"message": sandboxReceipt(entry.id)},
+	}, nil
+}
+
+func (sandbox *Sandbox) respond(path string, params []byte) (map[string]interface{}, error) {
+	var request struct {
+		Uid string `json:"uid"`
+	}
+	json.Unmarshal(params, &request)
+
+	sandbox.mu.Lock()
+	defer sandbox.mu.Unlock()
+
+	var data map[string]interface{}
+	switch path {
+	case "get_message_receipt.json":
+		entry, ok := sandbox.entries[request.Uid]
+		if !ok {
+			return sandboxStatus("not_found", request.Uid), nil
+		}
+		data = map[string]interface{}{"message": sandboxReceipt(entry.id)}
+	case "get_message_transmissions.json":
+		entry, ok := sandbox.entries[request.Uid]
+		if !ok {
+			return sandboxStatus("not_found", request.Uid), nil
+		}
+		transmissions := map[string]interface{}{}
+		for _, email := range entry.recipients {
+			transmissions[email] = map[string]interface{}{
+				"status":        "completed",
+				"created_at":    entry.createdAt.Format(time.RFC3339),
+				"failed_at":     nil,
+				"opened_at":     nil,
+				"result_code":   "SMTP_250",
+				"error_message": "2.0.0 OK sandbox",
+			}
+		}
+		data = map[string]interface{}{
+			"message":       map[string]interface{}{"id": float64(entry.id)},
+			"transmissions": transmissions,
+		}
+	case "get_messages.json":
+		data = map[string]interface{}{}
+		for _, uid := range sandbox.order {
+			entry := sandbox.entries[uid]
+			count := float64(len(entry.recipients))
+			data[uid] = map[string]interface{}{
+				"id":                      float64(entry.id),
+				"project_id":              float64(0),
+				"template":                entry.template,
+				"transmissions_total":     count,
+				"transmissions_failed":    float64(0),
+				"transmissions_completed": count,
+				"created_at":              entry.createdAt.Format(time.RFC3339),
+				"will_purge_at":           entry.createdAt.AddDate(0, 0, 30).Format(time.RFC3339),
+			}
+		}
+	case "get_project_info.json":
+		data = map[string]interface{}{"project": sandbox.info()}
+	case "get_account_info.json":
+		data = map[string]interface{}{"account": sandbox.info()}
+	case "get_metrics.json":
+		data = map[string]interface{}{"metrics": sandbox.metrics()}
+	default:
+		return sandboxStatus("not_found", request.Uid), nil
+	}
+
+	return map[string]interface{}{
+		"response": map[string]interface{}{"status": "ok", "uid": request.Uid},
+		"data":     data,
+	}, nil
+}
+
+func (sandbox *Sandbox) transmissionCount() float64 {
+	return float64(sandbox.transmissions)
+}
+
+func (sandbox *Sandbox) info() map[string]interface{} {
+	count := sandbox.transmissionCount()
+	return map[string]interface{}{
+		"name": "Sandbox",
+		"url":  "",
+		"transmissions": map[string]interface{}{
+			"today":      count,
+			"this_month": count,
+			"overall":    count,
+		},
+		"users": map[string]interface{}{},
+	}
+}
+
+func (sandbox *Sandbox) metrics() map[string]interface{} {
+	count := sandbox.transmissionCount()
+	metrics := map[string]interface{}{}
+	for _, period := range MetricPeriods {
+		metric := map[string]interface{}{}
+		for _, name := range MetricStatistics {
+			statistic := map[string]interface{}{
+				"current_percent":  0.0,
+				"previous_percent": 0.0,
+				"diff_percent":     0.0,
+				"current_value":    0.0,
+				"previous_value":   0.0,
+			}
+			if name == "delivered" || name == "created" {
+				statistic["current_value"] = count
+				if count > 0 {
+					statistic["current_percent"] = 100.0
+				}
+			}
+			metric[name] = statistic
+		}
+		metrics[period] = metric
+	}
+	return metrics
+}
+
+func sandboxReceipt(id int) map[string]interface{} {
+	return map[string]interface{}{
+		"id":  float64(id),
+		"url": fmt.Sprintf("sandbox://messages/%d", id),
+	}
+}
+
+func sandboxStatus(status string, uid string) map[string]interface{} {
+	return map[string]interface{}{
+		"response": map[string]interface{}{"status": status, "uid": uid},
+	}
+}
@@ -0,0 +1,221 @@
+package postage_app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func InitSandboxMessage() (*Client, *MemorySink, *Message) {
+	sink := new(MemorySink)
+	cl := new(Client)
+	cl.ApiKey = ApiKey
+	cl.sandbox = NewSandbox(sink)
+
+	message := new(Message)
+	message.Uid = "6e36017c-b662-441b-92cb-3acba3d556f4"
+	recipient := new(Recipient)
+	recipient.Email = "test@null.postageapp.com"
+	message.Recipients = append(message.Recipients, recipient)
+	message.Text = "This is my text content"
+	return cl, sink, message
+}
+
+func TestSandboxSendMessage(t *testing.T) {
+	cl, sink, message := InitSandboxMessage()
+	response, err := cl.SendMessage(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if response.Response.Status != "ok" || response.Response.Uid != message.Uid {
+		t.Log(response.Response)
+		t.Fail()
+	}
+
+	if response.Data.Id != 1 {
+		t.Log(response.Data.Id)
+		t.Fail()
+	}
+
+	records := sink.Records()
+	if len(records) != 1 || records[0].Message != message {
+		t.Log(records)
+		t.Fail()
+	}
+
+	if !strings.Contains(string(records[0].Payload), `"text/plain":"This is my text content"`) {
+		t.Log(string(records[0].Payload))
+		t.Fail()
+	}
+
+	again, _ := cl.SendMessage(message)
+	if again.Data.Id != 1 || len(sink.Records()) != 1 {
+		t.Log("Resending the same uid recorded a new message")
+		t.Fail()
+	}
+}
+
+func TestSandboxSendMessageBadRequest(t *testing.T) {
+	cl, sink, _ := InitSandboxMessage()
+	_, err := cl.SendMessage(new(Message))
+	if err == nil || err.Error() != "bad_request" {
+		t.Log("Error is not 'bad_request', is", err)
+		t.Fail()
+	}
+
+	if len(sink.Records()) != 0 {
+		t.Fail()
+	}
+}
+
+func TestSandboxOtherMethods(t *testing.T) {
+	cl, _, message := InitSandboxMessage()
+	cl.SendMessage(message)
+
+	receipt, err := cl.GetMessageReceipt(message.Uid)
+	if err != nil || receipt.Data.Id != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+
+	transmissions, err := cl.GetMessageTransmissions(message.Uid)
+	if err != nil || transmissions.Data.Transmissions["test@null.postageapp.com"].Status != "completed" {
+		t.Log(err)
+		t.Fail()
+	}
+
+	_, err = cl.GetMessageTransmissions("strange UID")
+	if err == nil || err.Error() != "not_found" {
+		t.Log("Error is not 'not_found'", err)
+		t.Fail()
+	}
+
+	messages, err := cl.GetMessages()
+	if err != nil || messages.Data[message.Uid] == nil || messages.Data[message.Uid].TotalTransmissionsCount != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+
+	project, err := cl.GetProjectInfo()
+	if err != nil || project.Data.Transmissions.OverallCount != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+
+	account, err := cl.GetAccountInfo()
+	if err != nil || account.Data.Name != "Sandbox" {
+		t.Log(err)
+		t.Fail()
+	}
+
+	metrics, err := cl.GetMetrics()
+	if err != nil || metrics.Data.Hour.Delivered.CurrentValue != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestSandboxDirectorySink(t *testing.T) {
+	dir := t.TempDir()
+	cl, _, message := InitSandboxMessage()
+	cl.sandbox.Sink = NewDirectorySink(dir)
+	message.Uid = "order/555"
+
+	if _, err := cl.SendMessage(message); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"order_555.json", "order_555.eml"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Log(err)
+			t.Fail()
+		}
+	}
+
+	eml, _ := os.ReadFile(filepath.Join(dir, "order_555.eml"))
+	if !strings.Contains(string(eml), "\r\nDate: ") || message.Headers["Date"] != "" {
+		t.Log(string(eml))
+		t.Fail()
+	}
+}
+
+func TestSandboxDirectorySinkStaysInDir(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "out")
+	cl, _, message := InitSandboxMessage()
+	cl.sandbox.Sink = NewDirectorySink(dir)
+
+	for _, uid := range []string{"..", ".", "../escape", "...hidden"} {
+		message.Uid = uid
+		if _, err := cl.SendMessage(message); err != nil {
+			t.Log(uid, err)
+			t.Fail()
+		}
+	}
+
+	entries, _ := os.ReadDir(parent)
+	if len(entries) != 1 || entries[0].Name() != "out" {
+		t.Log(entries)
+		t.Fail()
+	}
+	written, _ := os.ReadDir(dir)
+	for _, entry := range written {
+		if strings.HasPrefix(entry.Name(), ".") {
+			t.Log(entry.Name())
+			t.Fail()
+		}
+	}
+	if len(written) != 8 {
+		t.Log(written)
+		t.Fail()
+	}
+}
+
+func TestSandboxSinkFunc(t *testing.T) {
+	cl, _, message := InitSandboxMessage()
+	var recorded *SandboxRecord
+	cl.sandbox.Sink = SinkFunc(func(record *SandboxRecord) error {
+		recorded = record
+		return nil
+	})
+
+	cl.SendMessage(message)
+	if recorded == nil || recorded.Uid != message.Uid {
+		t.Log(recorded)
+		t.Fail()
+	}
+}
+
+func TestSandboxCapsMessageIndex(t *testing.T) {
+	cl, sink, message := InitSandboxMessage()
+	cl.sandbox.MaxMessages = 2
+	for _, uid := range []string{"first", "second", "third"} {
+		message.Uid = uid
+		if _, err := cl.SendMessage(message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sink.Records()) != 3 || len(cl.sandbox.entries) != 2 || len(cl.sandbox.order) != 2 {
+		t.Log(len(sink.Records()), len(cl.sandbox.entries), cl.sandbox.order)
+		t.Fail()
+	}
+	if _, err := cl.GetMessageReceipt("first"); err == nil {
+		t.Log("evicted message should not be found")
+		t.Fail()
+	}
+	if response, err := cl.GetMessageTransmissions("third"); err != nil || len(response.Data.Transmissions) != 1 {
+		t.Log(response, err)
+		t.Fail()
+	}
+	if response, err := cl.GetMessages(); err != nil || len(response.Data) != 2 {
+		t.Log(response, err)
+		t.Fail()
+	}
+	if response, err := cl.GetProjectInfo(); err != nil || response.Data.Transmissions.OverallCount != 3 {
+		t.Log(response, err)
+		t.Fail()
+	}
+}
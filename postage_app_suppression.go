@@ -0,0 +1,322 @@
+package postage_app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Suppression struct {
+	Email      string    `json:"email"`
+	Reason     string    `json:"reason"`
+	ResultCode string    `json:"result_code,omitempty"`
+	MessageUid string    `json:"message_uid,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+}
+
+func (suppression *Suppression) Expired(now time.Time) bool {
+	return !suppression.ExpiresAt.IsZero() && !now.Before(suppression.ExpiresAt)
+}
+
+type SuppressionStore interface {
+	Get(email string) (*Suppression, error)
+	Put(suppression *Suppression) error
+	Delete(email string) error
+	List() ([]*Suppression, error)
+}
+
+type SuppressionError struct {
+	Suppressed []*Suppression
+}
+
+func (e *SuppressionError) Error() string {
+	emails := make([]string, len(e.Suppressed))
+	for i, suppression := range e.Suppressed {
+		emails[i] = suppression.Email
+	}
+	return "suppression_list: suppressed recipients: " + strings.Join(emails, ", ")
+}
+
+func normalizeEmail(email string) string {
+	if parsed, err := mail.ParseAddress(email); err == nil {
+		email = parsed.Address
+	}
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+type MemorySuppressionStore struct {
+	mu      sync.Mutex
+	entries map[string]*Suppression
+}
+
+func NewMemorySuppressionStore() *MemorySuppressionStore {
+	store := new(MemorySuppressionStore)
+	store.entries = make(map[string]*Suppression)
+	return store
+}
+
+func (store *MemorySuppressionStore) Get(email string) (*Suppression, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return store.entries[normalizeEmail(email)], nil
+}
+
+func (store *MemorySuppressionStore) Put(suppression *Suppression) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.entries[normalizeEmail(suppression.Email)] = suppression
+	return nil
+}
+
+func (store *MemorySuppressionStore) Delete(email string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	delete(store.entries, normalizeEmail(email))
+	return nil
+}
+
+func (store *MemorySuppressionStore) List() ([]*Suppression, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	list := make([]*Suppression, 0, len(store.entries))
+	for _, suppression := range store.entries {
+		list = append(list, suppression)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Email < list[j].Email })
+	return list, nil
+}
+
+type FileSuppressionStore struct {
+	*MemorySuppressionStore
+	Path string
+
+	mu sync.Mutex
+}
+
+func NewFileSuppressionStore(path string) (*FileSuppressionStore, error) {
+	store := new(FileSuppressionStore)
+	store.MemorySuppressionStore = NewMemorySuppressionStore()
+	store.Path = path
+
+	bts, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Suppression
+	if err := json.Unmarshal(bts, &list); err != nil {
+		return nil, &PostageError{fmt.Sprintf("suppression_list: %s: %s", path, err), err}
+	}
+	for _, suppression := range list {
+		store.entries[normalizeEmail(suppression.Email)] = suppression
+	}
+	return store, nil
+}
+
+func (store *FileSuppressionStore) Put(suppression *Suppression) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.MemorySuppressionStore.Put(suppression)
+	return store.save()
+}
+
+func (store *FileSuppressionStore) Delete(email string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	store.MemorySuppressionStore.Delete(email)
+	return store.save()
+}
+
+func (store *FileSuppressionStore) save() error {
+	list, _ := store.List()
+	bts, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(store.Path), filepath.Base(store.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(bts); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), store.Path)
+}
+
+type SuppressionList struct {
+	Store         SuppressionStore
+	Refuse        bool
+	HardBounceTTL time.Duration
+	SoftBounceTTL time.Duration
+	SoftBounces   bool
+
+	now func() time.Time
+}
+
+func NewSuppressionList(store SuppressionStore) *SuppressionList {
+	list := new(SuppressionList)
+	if store == nil {
+		store = NewMemorySuppressionStore()
+	}
+	list.Store = store
+	return list
+}
+
+func (list *SuppressionList) clock() time.Time {
+	if list.now != nil {
+		return list.now()
+	}
+	return time.Now()
+}
+
+func (list *SuppressionList) Suppress(email string, reason string, ttl time.Duration) error {
+	suppression := new(Suppression)
+	suppression.Email = normalizeEmail(email)
+	suppression.Reason = reason
+	suppression.CreatedAt = list.clock()
+	if ttl > 0 {
+		suppression.ExpiresAt = suppression.CreatedAt.Add(ttl)
+	}
+	return list.Store.Put(suppression)
+}
+
+func (list *SuppressionList) Unsuppress(email string) error {
+	return list.Store.Delete(email)
+}
+
+func (list *SuppressionList) Check(email string) (*Suppression, error) {
+	suppression, err := list.Store.Get(email)
+	if err != nil || suppression == nil {
+		return nil, err
+	}
+	if suppression.Expired(list.clock()) {
+		return nil, list.Store.Delete(email)
+	}
+	return suppression, nil
+}
+
+func (list *SuppressionList) Apply(message *Message) (*Message, []*Suppression, error) {
+	applied := *message
+	applied.Recipients = nil
+
+	var suppressed []*Suppression
+	for _, recipient := range message.Recipients {
+		suppression, err := list.Check(recipient.Email)
+		if err != nil {
+			return nil, nil, &PostageError{"suppression_list: " + err.Error(), err}
+		}
+		if suppression != nil {
+			suppressed = append(suppressed, suppression)
+			continue
+		}
+		applied.Recipients = append(applied.Recipients, recipient)
+	}
+
+	if len(suppressed) != 0 && (list.Refuse || len(applied.Recipients) == 0) {
+		return nil, suppressed, &SuppressionError{suppressed}
+	}
+	return &applied, suppressed, nil
+}
+
+func (list *SuppressionList) Record(uid string, transmissions *MessageTransmissions) (int, error) {
+	added := 0
+	for email, transmission := range transmissions.Transmissions {
+		if !transmission.Status.IsFailure() {
+			continue
+		}
+
+		ttl := list.HardBounceTTL
+		switch transmission.Bounce() {
+		case BounceHard:
+		case BounceSoft:
+			if !list.SoftBounces {
+				continue
+			}
+			ttl = list.SoftBounceTTL
+		default:
+			continue
+		}
+
+		existing, err := list.Check(email)
+		if err != nil {
+			return added, err
+		}
+		if existing != nil {
+			continue
+		}
+
+		reason := transmission.ResultMessage
+		if reason == "" {
+			reason = string(transmission.Status)
+		}
+		suppression := new(Suppression)
+		suppression.Email = normalizeEmail(email)
+		suppression.Reason = reason
+		suppression.ResultCode = transmission.ResultCode
+		suppression.MessageUid = uid
+		suppression.CreatedAt = list.clock()
+		if ttl > 0 {
+			suppression.ExpiresAt = suppression.CreatedAt.Add(ttl)
+		}
+		if err := list.Store.Put(suppression); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+func (list *SuppressionList) Sync(ctx context.Context, client *Client, since time.Time) (int, error) {
+	opts := new(MessagesOptions)
+	opts.Since = since
+	opts.HasFailures = true
+
+	added := 0
+	for info, err := range client.Messages(ctx, opts) {
+		if err != nil {
+			return added, err
+		}
+		response, err := client.GetMessageTransmissionsContext(ctx, info.Uid)
+		if err != nil {
+			return added, err
+		}
+		n, err := list.Record(info.Uid, response.Data)
+		added += n
+		if err != nil {
+			return added, err
+		}
+	}
+	return added, nil
+}
+
+func (list *SuppressionList) Watch(ctx context.Context, client *Client, interval time.Duration, lookback time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := list.Sync(ctx, client, list.clock().Add(-lookback)); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package postage_app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func InitSuppressionList() (*SuppressionList, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	list := NewSuppressionList(nil)
+	list.now = func() time.Time { return now }
+	return list, &now
+}
+
+func InitSuppressionMessage() *Message {
+	message := new(Message)
+	message.Text = "hello"
+	for _, email := range []string{"kept@example.com", "Bounced <Bounced@Example.com>"} {
+		recipient := new(Recipient)
+		recipient.Email = email
+		message.Recipients = append(message.Recipients, recipient)
+	}
+	return message
+}
+
+func TestSuppressionListDropsRecipients(t *testing.T) {
+	list, now := InitSuppressionList()
+	list.Suppress("bounced@example.com", "manual", time.Hour)
+
+	message := InitSuppressionMessage()
+	applied, suppressed, err := list.Apply(message)
+	if err != nil || len(applied.Recipients) != 1 || applied.Recipients[0].Email != "kept@example.com" {
+		t.Log(applied, err)
+		t.Fail()
+	}
+	if len(suppressed) != 1 || suppressed[0].Email != "bounced@example.com" || suppressed[0].Reason != "manual" {
+		t.Log(suppressed)
+		t.Fail()
+	}
+	if len(message.Recipients) != 2 {
+		t.Log("original message should not be modified")
+		t.Fail()
+	}
+
+	*now = now.Add(time.Hour)
+	if applied, suppressed, err := list.Apply(message); err != nil || len(applied.Recipients) != 2 || len(suppressed) != 0 {
+		t.Log("expired suppression should not apply", suppressed, err)
+		t.Fail()
+	}
+	if entries, _ := list.Store.List(); len(entries) != 0 {
+		t.Log(entries)
+		t.Fail()
+	}
+}
+
+func TestSuppressionListRefuses(t *testing.T) {
+	list, _ := InitSuppressionList()
+	list.Suppress("bounced@example.com", "manual", 0)
+	list.Refuse = true
+
+	_, suppressed, err := list.Apply(InitSuppressionMessage())
+	var refused *SuppressionError
+	if !errors.As(err, &refused) || len(refused.Suppressed) != 1 || len(suppressed) != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+
+	list.Refuse = false
+	list.Suppress("kept@example.com", "manual", 0)
+	if _, _, err := list.Apply(InitSuppressionMessage()); !errors.As(err, &refused) || len(refused.Suppressed) != 2 {
+		t.Log("all recipients suppressed should refuse", err)
+		t.Fail()
+	}
+}
+
+func TestSuppressionListRecord(t *testing.T) {
+	list, now := InitSuppressionList()
+	transmissions := new(MessageTransmissions)
+	transmissions.Transmissions = map[string]*MessageTransmission{
+		"hard@example.com":      {Status: TransmissionFailed, ResultCode: "SMTP_550", ResultMessage: "5.1.1 user unknown"},
+		"soft@example.com":      {Status: TransmissionFailed, ResultCode: "SMTP_421", ResultMessage: "try again later"},
+		"delivered@example.com": {Status: TransmissionCompleted, ResultCode: "SMTP_250"},
+	}
+
+	if added, err := list.Record("abc", transmissions); added != 1 || err != nil {
+		t.Log(added, err)
+		t.Fail()
+	}
+	suppression, _ := list.Check("HARD@example.com")
+	if suppression == nil || suppression.Reason != "5.1.1 user unknown" || suppression.MessageUid != "abc" || !suppression.ExpiresAt.IsZero() {
+		t.Log(suppression)
+		t.Fail()
+	}
+
+	list.SoftBounces = true
+	list.SoftBounceTTL = 24 * time.Hour
+	if added, err := list.Record("abc", transmissions); added != 1 || err != nil {
+		t.Log("only the soft bounce should be new", added, err)
+		t.Fail()
+	}
+	if suppression, _ := list.Check("soft@example.com"); suppression == nil || !suppression.ExpiresAt.Equal(now.Add(24*time.Hour)) {
+		t.Log(suppression)
+		t.Fail()
+	}
+}
+
+func TestSendMessageReportsSuppressed(t *testing.T) {
+	cl, sink, _ := InitSandboxMessage()
+	cl.suppressions, _ = InitSuppressionList()
+	cl.suppressions.Suppress("bounced@example.com", "manual", 0)
+
+	response, err := cl.SendMessage(InitSuppressionMessage())
+	if err != nil || len(response.Suppressed) != 1 || response.Suppressed[0].Email != "bounced@example.com" {
+		t.Log(response, err)
+		t.Fail()
+	}
+	if records := sink.Records(); len(records) != 1 || len(records[0].Message.Recipients) != 1 {
+		t.Log(records)
+		t.Fail()
+	}
+}
+
+func TestSuppressionListSync(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/get_messages.json"):
+			w.Write([]byte(`{"response":{"status":"ok"},"data":{
+				"ok":{"created_at":"2024-01-01 00:00:00","transmissions_total":1,"transmissions_failed":0},
+				"bad":{"created_at":"2024-01-01 00:00:00","transmissions_total":2,"transmissions_failed":1}}}`))
+		case strings.HasSuffix(r.URL.Path, "/get_message_transmissions.json"):
+			var params map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&params)
+			if params["uid"] != "bad" {
+				t.Log("unexpected uid", params["uid"])
+				t.Fail()
+			}
+			w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1},"transmissions":{
+				"gone@example.com":{"status":"failed","created_at":"2024-01-01 00:00:00","result_code":"SMTP_550","error_message":"no such user"},
+				"fine@example.com":{"status":"completed","created_at":"2024-01-01 00:00:00","result_code":"SMTP_250"}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cl, _ := NewClient(ApiKey, WithBaseURL(server.URL))
+	list, _ := InitSuppressionList()
+	added, err := list.Sync(context.Background(), cl, time.Time{})
+	if err != nil || added != 1 {
+		t.Log(added, err)
+		t.Fail()
+	}
+	if suppression, _ := list.Check("gone@example.com"); suppression == nil || suppression.ResultCode != "SMTP_550" {
+		t.Log(suppression)
+		t.Fail()
+	}
+}
+
+func TestFileSuppressionStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "suppressions.json")
+	store, err := NewFileSuppressionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := NewSuppressionList(store)
+	list.Suppress("one@example.com", "manual", 0)
+	list.Suppress("two@example.com", "manual", 0)
+	list.Unsuppress("one@example.com")
+
+	reopened, err := NewFileSuppressionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := reopened.List()
+	if len(entries) != 1 || entries[0].Email != "two@example.com" {
+		t.Log(entries)
+		t.Fail()
+	}
+	if bts, _ := os.ReadFile(path); strings.Contains(string(bts), "expires_at") {
+		t.Log(string(bts))
+		t.Fail()
+	}
+}
+
+func TestFileSuppressionStoreConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "suppressions.json")
+	store, _ := NewFileSuppressionStore(path)
+	list := NewSuppressionList(store)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := list.Suppress(fmt.Sprintf("user%d@example.com", i), "manual", 0); err != nil {
+				t.Log(err)
+				t.Fail()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	reopened, err := NewFileSuppressionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := reopened.List(); len(entries) != 20 {
+		t.Log(len(entries))
+		t.Fail()
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 1 {
+		t.Log(files)
+		t.Fail()
+	}
+}
@@ -0,0 +1,178 @@
+package postage_app
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.\-]+)\s*\}\}`)
+
+type Template struct {
+	Subject string
+	Text    string
+	Html    string
+}
+
+type RenderedMessage struct {
+	Recipient  *Recipient
+	Subject    string
+	Text       string
+	Html       string
+	Unresolved []string
+}
+
+type Renderer struct {
+	Templates fs.FS
+	Strict    bool
+}
+
+type TemplateError PostageError
+
+func (e *TemplateError) Error() string {
+	return e.Message
+}
+
+func NewRenderer(templates fs.FS) *Renderer {
+	renderer := new(Renderer)
+	renderer.Templates = templates
+	return renderer
+}
+
+func NewFileRenderer(dir string) *Renderer {
+	return NewRenderer(os.DirFS(dir))
+}
+
+func (renderer *Renderer) LoadTemplate(slug string) (*Template, error) {
+	if renderer.Templates == nil {
+		return nil, &TemplateError{"template: no template source configured", nil}
+	}
+	if !fs.ValidPath(slug) {
+		return nil, &TemplateError{"template: invalid slug " + slug, nil}
+	}
+
+	template := new(Template)
+	found := false
+	for _, file := range []struct {
+		ext    string
+		target *string
+	}{{".subject", &template.Subject}, {".txt", &template.Text}, {".html", &template.Html}} {
+		content, err := fs.ReadFile(renderer.Templates, slug+file.ext)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, &TemplateError{"template: " + err.Error(), err}
+		}
+		value := string(content)
+		if file.ext == ".subject" {
+			value = strings.TrimSpace(value)
+		}
+		*file.target = value
+		found = true
+	}
+	if !found {
+		return nil, &TemplateError{"template: " + slug + " not found", fs.ErrNotExist}
+	}
+	return template, nil
+}
+
+func (renderer *Renderer) Render(message *Message) ([]*RenderedMessage, error) {
+	template := new(Template)
+	if message.Template != "" {
+		loaded, err := renderer.LoadTemplate(message.Template)
+		if err != nil {
+			return nil, err
+		}
+		template = loaded
+	}
+	if message.Subject != "" {
+		template.Subject = message.Subject
+	}
+	if message.Text != "" {
+		template.Text = message.Text
+	}
+	if message.Html != "" {
+		template.Html = message.Html
+	}
+
+	recipients := message.Recipients
+	if len(recipients) == 0 {
+		recipients = []*Recipient{nil}
+	}
+
+	rendered := make([]*RenderedMessage, 0, len(recipients))
+	for _, recipient := range recipients {
+		variables := message.recipientVariables(recipient)
+		unresolved := make(map[string]bool)
+
+		result := new(RenderedMessage)
+		result.Recipient = recipient
+		result.Subject = renderPlaceholders(template.Subject, variables, unresolved)
+		result.Text = renderPlaceholders(template.Text, variables, unresolved)
+		result.Html = renderPlaceholders(template.Html, variables, unresolved)
+		for name := range unresolved {
+			result.Unresolved = append(result.Unresolved, name)
+		}
+		sort.Strings(result.Unresolved)
+
+		if renderer.Strict && len(result.Unresolved) != 0 {
+			email := ""
+			if recipient != nil {
+				email = " for " + recipient.Email
+			}
+			return nil, &TemplateError{"template: unresolved variables" + email + ": " + strings.Join(result.Unresolved, ", "), nil}
+		}
+		rendered = append(rendered, result)
+	}
+	return rendered, nil
+}
+
+func (renderer *Renderer) WriteMIME(w io.Writer, message *Message, recipient *Recipient) error {
+	preview := *message
+	preview.Recipients = []*Recipient{recipient}
+	if recipient == nil {
+		preview.Recipients = nil
+	}
+
+	rendered, err := renderer.Render(&preview)
+	if err != nil {
+		return err
+	}
+	preview.Template = ""
+	preview.Variables = nil
+	preview.Subject = rendered[0].Subject
+	preview.Text = rendered[0].Text
+	preview.Html = rendered[0].Html
+	return preview.WriteMIME(w, recipient)
+}
+
+func (message *Message) recipientVariables(recipient *Recipient) map[string]string {
+	variables := make(map[string]string)
+	for key, value := range message.Variables {
+		variables[key] = value
+	}
+	if recipient != nil {
+		for key, value := range recipient.Variables {
+			variables[key] = value
+		}
+	}
+	return variables
+}
+
+func renderPlaceholders(content string, variables map[string]string, unresolved map[string]bool) string {
+	return placeholderPattern.ReplaceAllStringFunc(content, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		if unresolved != nil {
+			unresolved[name] = true
+		}
+		return placeholder
+	})
+}
@@ -0,0 +1,140 @@
+package postage_app
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func InitRenderer() *Renderer {
+	return NewRenderer(fstest.MapFS{
+		"order-shipped.subject": {Data: []byte("Order {{order_id}} has shipped\n")},
+		"order-shipped.txt":     {Data: []byte("Hi {{first_name}}, order {{order_id}} from {{store}} is on its way.")},
+		"order-shipped.html":    {Data: []byte("<p>Hi {{ first_name }}</p>")},
+		"text-only.txt":         {Data: []byte("Hi {{first_name}}")},
+	})
+}
+
+func TestRenderTemplatePerRecipient(t *testing.T) {
+	renderer := InitRenderer()
+	_, message := InitMessage()
+	message.Template = "order-shipped"
+	message.Variables = map[string]string{"first_name": "Friend", "store": "Acme", "order_id": "0"}
+
+	recipient := new(Recipient)
+	recipient.Email = "alan.smithee@gmail.com"
+	recipient.Variables = map[string]string{"first_name": "Alan", "order_id": "555"}
+	recipient2 := new(Recipient)
+	recipient2.Email = "rick.james@gmail.com"
+	recipient2.Variables = map[string]string{"order_id": "556"}
+	message.Recipients = append(message.Recipients, recipient, recipient2)
+
+	rendered, err := renderer.Render(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rendered) != 2 {
+		t.Fatal(len(rendered))
+	}
+
+	if rendered[0].Subject != "Order 555 has shipped" {
+		t.Log(rendered[0].Subject)
+		t.Fail()
+	}
+
+	if rendered[0].Text != "Hi Alan, order 555 from Acme is on its way." {
+		t.Log(rendered[0].Text)
+		t.Fail()
+	}
+
+	if rendered[0].Html != "<p>Hi Alan</p>" {
+		t.Log(rendered[0].Html)
+		t.Fail()
+	}
+
+	if rendered[1].Text != "Hi Friend, order 556 from Acme is on its way." {
+		t.Log(rendered[1].Text)
+		t.Fail()
+	}
+
+	if rendered[1].Recipient != recipient2 || len(rendered[1].Unresolved) != 0 {
+		t.Log(rendered[1].Unresolved)
+		t.Fail()
+	}
+}
+
+func TestRenderMessageContentOverridesTemplate(t *testing.T) {
+	renderer := InitRenderer()
+	_, message := InitMessage()
+	message.Template = "order-shipped"
+	message.Subject = "Custom subject for {{first_name}}"
+	message.Variables = map[string]string{"first_name": "Alan"}
+
+	rendered, err := renderer.Render(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rendered[0].Subject != "Custom subject for Alan" {
+		t.Log(rendered[0].Subject)
+		t.Fail()
+	}
+
+	if strings.Join(rendered[0].Unresolved, ",") != "order_id,store" {
+		t.Log(rendered[0].Unresolved)
+		t.Fail()
+	}
+}
+
+func TestRenderStrictReportsUnresolved(t *testing.T) {
+	renderer := InitRenderer()
+	renderer.Strict = true
+	_, message := InitMessage()
+	message.Template = "text-only"
+
+	_, err := renderer.Render(message)
+	if err == nil {
+		t.Log("Error is nil")
+		t.Fail()
+	} else if err.Error() != "template: unresolved variables: first_name" {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRenderUnknownTemplate(t *testing.T) {
+	renderer := InitRenderer()
+	_, message := InitMessage()
+	message.Template = "some-unknown-template"
+
+	_, err := renderer.Render(message)
+	if _, ok := err.(*TemplateError); !ok {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRendererWriteMIME(t *testing.T) {
+	renderer := InitRenderer()
+	_, message := InitMessage()
+	message.Template = "text-only"
+	recipient := new(Recipient)
+	recipient.Email = "alan.smithee@gmail.com"
+	recipient.Variables = map[string]string{"first_name": "Alan"}
+
+	var b strings.Builder
+	if err := renderer.WriteMIME(&b, message, recipient); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ParseMIME(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parsed.Text != "Hi Alan" {
+		t.Log(parsed.Text)
+		t.Fail()
+	}
+}
@@ -0,0 +1,90 @@
+package postage_app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 UTC",
+	"2006-01-02 15:04:05 GMT",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+}
+
+type TimeError struct {
+	Field string
+	Value string
+}
+
+func (e *TimeError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("cannot parse time %q", e.Value)
+	}
+	return fmt.Sprintf("%s: cannot parse time %q", e.Field, e.Value)
+}
+
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, &TimeError{"", value}
+}
+
+type NullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t NullTime) MarshalJSON() ([]byte, error) {
+	if !t.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time.Format(time.RFC3339Nano))
+}
+
+func (t *NullTime) UnmarshalJSON(data []byte) error {
+	var value *string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	if value == nil || *value == "" {
+		*t = NullTime{}
+		return nil
+	}
+	parsed, err := ParseTime(*value)
+	if err != nil {
+		return err
+	}
+	*t = NullTime{parsed, true}
+	return nil
+}
+
+func decodeTime(object map[string]interface{}, key string, field string) (NullTime, error) {
+	switch value := object[key].(type) {
+	case nil:
+		return NullTime{}, nil
+	case string:
+		if strings.TrimSpace(value) == "" {
+			return NullTime{}, nil
+		}
+		t, err := ParseTime(value)
+		if err != nil {
+			return NullTime{}, &TimeError{field, value}
+		}
+		return NullTime{t, true}, nil
+	default:
+		return NullTime{}, &TimeError{field, fmt.Sprint(value)}
+	}
+}
@@ -0,0 +1,140 @@
+package postage_app
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type TransmissionStatus string
+
+const (
+	TransmissionQueued    TransmissionStatus = "queued"
+	TransmissionCompleted TransmissionStatus = "completed"
+	TransmissionFailed    TransmissionStatus = "failed"
+	TransmissionRejected  TransmissionStatus = "rejected"
+	TransmissionOpened    TransmissionStatus = "opened"
+	TransmissionClicked   TransmissionStatus = "clicked"
+	TransmissionSpammed   TransmissionStatus = "spammed"
+)
+
+var TransmissionStatuses = []TransmissionStatus{
+	TransmissionQueued, TransmissionCompleted, TransmissionFailed, TransmissionRejected,
+	TransmissionOpened, TransmissionClicked, TransmissionSpammed,
+}
+
+var transmissionTransitions = map[TransmissionStatus][]TransmissionStatus{
+	TransmissionQueued:    {TransmissionCompleted, TransmissionFailed, TransmissionRejected, TransmissionOpened, TransmissionClicked, TransmissionSpammed},
+	TransmissionCompleted: {TransmissionOpened, TransmissionClicked, TransmissionSpammed},
+	TransmissionOpened:    {TransmissionClicked, TransmissionSpammed},
+	TransmissionClicked:   {TransmissionSpammed},
+}
+
+func ParseTransmissionStatus(status string) TransmissionStatus {
+	return TransmissionStatus(strings.ToLower(strings.TrimSpace(status)))
+}
+
+func (status TransmissionStatus) IsKnown() bool {
+	for _, known := range TransmissionStatuses {
+		if status == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (status TransmissionStatus) IsTerminal() bool {
+	return status.IsKnown() && len(transmissionTransitions[status]) == 0
+}
+
+func (status TransmissionStatus) IsSettled() bool {
+	return status.IsKnown() && status != TransmissionQueued
+}
+
+func (status TransmissionStatus) IsDelivered() bool {
+	return status.IsSuccess() || status == TransmissionSpammed
+}
+
+func (status TransmissionStatus) IsSuccess() bool {
+	return status == TransmissionCompleted || status == TransmissionOpened || status == TransmissionClicked
+}
+
+func (status TransmissionStatus) IsFailure() bool {
+	return status == TransmissionFailed || status == TransmissionRejected
+}
+
+func (status TransmissionStatus) CanTransitionTo(next TransmissionStatus) bool {
+	if status == next {
+		return true
+	}
+	for _, allowed := range transmissionTransitions[status] {
+		if next == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+type BounceType int
+
+const (
+	BounceNone BounceType = iota
+	BounceSoft
+	BounceHard
+	BounceUnknown
+)
+
+func (bounce BounceType) String() string {
+	switch bounce {
+	case BounceNone:
+		return "none"
+	case BounceSoft:
+		return "soft"
+	case BounceHard:
+		return "hard"
+	}
+	return "unknown"
+}
+
+var (
+	smtpCodePattern     = regexp.MustCompile(`\b([245])[0-9]{2}\b`)
+	enhancedCodePattern = regexp.MustCompile(`\b([245])\.[0-9]{1,3}\.[0-9]{1,3}\b`)
+)
+
+func SMTPCode(resultCode string) int {
+	match := smtpCodePattern.FindString(strings.ReplaceAll(resultCode, "_", " "))
+	if match == "" {
+		return 0
+	}
+	code, _ := strconv.Atoi(match)
+	return code
+}
+
+func ClassifyResult(resultCode string, resultMessage string) BounceType {
+	class := ""
+	if code := SMTPCode(resultCode); code != 0 {
+		class = strconv.Itoa(code / 100)
+	} else if match := enhancedCodePattern.FindStringSubmatch(resultMessage); match != nil {
+		class = match[1]
+	} else if match := smtpCodePattern.FindStringSubmatch(resultMessage); match != nil {
+		class = match[1]
+	}
+
+	switch class {
+	case "2":
+		return BounceNone
+	case "4":
+		return BounceSoft
+	case "5":
+		return BounceHard
+	}
+	return BounceUnknown
+}
+
+func (transmission *MessageTransmission) Bounce() BounceType {
+	bounce := ClassifyResult(transmission.ResultCode, transmission.ResultMessage)
+	if bounce == BounceUnknown && transmission.Status.IsSuccess() {
+		return BounceNone
+	}
+	return bounce
+}
@@ -0,0 +1,70 @@
+package postage_app
+
+import (
+	"testing"
+)
+
+func TestTransmissionStatus(t *testing.T) {
+	if ParseTransmissionStatus(" Completed ") != TransmissionCompleted {
+		t.Fail()
+	}
+	unknown := ParseTransmissionStatus("deferred")
+	if unknown != "deferred" || unknown.IsKnown() || unknown.IsTerminal() || unknown.IsSuccess() {
+		t.Log(unknown)
+		t.Fail()
+	}
+	if TransmissionQueued.IsTerminal() || !TransmissionFailed.IsTerminal() || !TransmissionFailed.IsFailure() {
+		t.Fail()
+	}
+	for _, status := range TransmissionStatuses {
+		for _, next := range TransmissionStatuses {
+			if status.IsTerminal() && status != next && status.CanTransitionTo(next) {
+				t.Log(status, "is terminal but can become", next)
+				t.Fail()
+			}
+		}
+	}
+	if TransmissionCompleted.IsTerminal() || !TransmissionCompleted.IsSettled() || TransmissionQueued.IsSettled() || unknown.IsSettled() {
+		t.Fail()
+	}
+	if !TransmissionSpammed.IsDelivered() || !TransmissionOpened.IsDelivered() || TransmissionFailed.IsDelivered() {
+		t.Fail()
+	}
+	if !TransmissionClicked.IsSuccess() || TransmissionSpammed.IsSuccess() || TransmissionRejected.IsSuccess() {
+		t.Fail()
+	}
+	if !TransmissionQueued.CanTransitionTo(TransmissionCompleted) || !TransmissionCompleted.CanTransitionTo(TransmissionOpened) ||
+		TransmissionFailed.CanTransitionTo(TransmissionCompleted) || TransmissionOpened.CanTransitionTo(TransmissionQueued) {
+		t.Fail()
+	}
+}
+
+func TestClassifyResult(t *testing.T) {
+	cases := []struct {
+		code     string
+		message  string
+		expected BounceType
+	}{
+		{"SMTP_250", "2.0.0 OK", BounceNone},
+		{"SMTP_550", "5.1.1 The email account that you tried to reach does not exist", BounceHard},
+		{"SMTP_421", "", BounceSoft},
+		{"", "452 4.2.2 Mailbox full", BounceSoft},
+		{"", "554 5.7.1 Message rejected", BounceHard},
+		{"", "connection timed out", BounceUnknown},
+	}
+	for _, c := range cases {
+		if bounce := ClassifyResult(c.code, c.message); bounce != c.expected {
+			t.Log(c.code, c.message, bounce)
+			t.Fail()
+		}
+	}
+	if SMTPCode("SMTP_550") != 550 || SMTPCode("OK") != 0 {
+		t.Fail()
+	}
+
+	transmission := &MessageTransmission{Status: TransmissionCompleted}
+	if transmission.Bounce() != BounceNone {
+		t.Log(transmission.Bounce())
+		t.Fail()
+	}
+}
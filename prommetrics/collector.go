@@ -0,0 +1,135 @@
+package prommetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+const Namespace = "postageapp_client"
+
+type Collector struct {
+	calls         *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	sentBytes     *prometheus.CounterVec
+	retries       *prometheus.CounterVec
+	rateLimitWait prometheus.Histogram
+	queueDepth    *prometheus.Desc
+
+	mu     sync.Mutex
+	queues map[string]func() int
+}
+
+func NewCollector() *Collector {
+	collector := new(Collector)
+	collector.calls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "calls_total",
+		Help:      "API calls by endpoint and PostageApp response status.",
+	}, []string{"endpoint", "status"})
+	collector.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "call_duration_seconds",
+		Help:      "API call latency including retries.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+	collector.sentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "sent_bytes_total",
+		Help:      "Request bytes sent, with attachment content counted separately.",
+	}, []string{"endpoint", "kind"})
+	collector.retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "retries_total",
+		Help:      "Retried API call attempts.",
+	}, []string{"endpoint"})
+	collector.rateLimitWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "rate_limit_wait_seconds",
+		Help:      "Time spent waiting on the client rate limiter.",
+		Buckets:   []float64{0, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	})
+	collector.queueDepth = prometheus.NewDesc(Namespace+"_queue_depth", "Messages waiting in a queue.", []string{"queue"}, nil)
+	collector.queues = make(map[string]func() int)
+	return collector
+}
+
+func (collector *Collector) Register(registerer prometheus.Registerer) error {
+	return registerer.Register(collector)
+}
+
+func (collector *Collector) WatchQueue(name string, depth func() int) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if depth == nil {
+		delete(collector.queues, name)
+		return
+	}
+	collector.queues[name] = depth
+}
+
+func (collector *Collector) Describe(descs chan<- *prometheus.Desc) {
+	collector.calls.Describe(descs)
+	collector.duration.Describe(descs)
+	collector.sentBytes.Describe(descs)
+	collector.retries.Describe(descs)
+	collector.rateLimitWait.Describe(descs)
+	descs <- collector.queueDepth
+}
+
+func (collector *Collector) Collect(metrics chan<- prometheus.Metric) {
+	collector.calls.Collect(metrics)
+	collector.duration.Collect(metrics)
+	collector.sentBytes.Collect(metrics)
+	collector.retries.Collect(metrics)
+	collector.rateLimitWait.Collect(metrics)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	for name, depth := range collector.queues {
+		metrics <- prometheus.MustNewConstMetric(collector.queueDepth, prometheus.GaugeValue, float64(depth()), name)
+	}
+}
+
+func (collector *Collector) Middleware() postage_app.Middleware {
+	return func(next postage_app.Handler) postage_app.Handler {
+		return func(call *postage_app.Call) (map[string]interface{}, error) {
+			started := time.Now()
+			m, err := next(call)
+
+			collector.duration.WithLabelValues(call.Endpoint).Observe(time.Since(started).Seconds())
+			collector.calls.WithLabelValues(call.Endpoint, status(m, err)).Inc()
+			if call.Attempts > 1 {
+				collector.retries.WithLabelValues(call.Endpoint).Add(float64(call.Attempts - 1))
+			}
+			if call.Attempts == 0 {
+				return m, err
+			}
+			collector.rateLimitWait.Observe(call.RateLimitWait.Seconds())
+
+			attachmentBytes := 0
+			if message, ok := call.Request.(*postage_app.Message); ok {
+				for _, attachment := range message.Attachments {
+					attachmentBytes += len(attachment.ContentBytes)
+				}
+			}
+			collector.sentBytes.WithLabelValues(call.Endpoint, "payload").Add(float64(len(call.Body)))
+			collector.sentBytes.WithLabelValues(call.Endpoint, "attachment").Add(float64(attachmentBytes))
+			return m, err
+		}
+	}
+}
+
+func status(m map[string]interface{}, err error) string {
+	response, _ := m["response"].(map[string]interface{})
+	if status, _ := response["status"].(string); status != "" {
+		return status
+	}
+	if err != nil {
+		return "error"
+	}
+	return "unknown"
+}
@@ -0,0 +1,135 @@
+package prommetrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+func TestCollectorMiddleware(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "get_metrics.json") {
+			w.Write([]byte(`{"response":{"status":"unauthorized"}}`))
+			return
+		}
+		w.Write([]byte(`{"response":{"status":"ok"},"data":{"message":{"id":1,"url":""}}}`))
+	}))
+	defer server.Close()
+
+	collector := NewCollector()
+	registry := prometheus.NewPedanticRegistry()
+	if err := collector.Register(registry); err != nil {
+		t.Fatal(err)
+	}
+	collector.WatchQueue("outbox", func() int { return 3 })
+
+	cl, _ := postage_app.NewClient("metrics-test-key", postage_app.WithBaseURL(server.URL),
+		postage_app.WithRetry(2, time.Millisecond, time.Millisecond), postage_app.WithMiddleware(collector.Middleware()))
+
+	message := new(postage_app.Message)
+	message.Text = "hello"
+	message.Recipients = []*postage_app.Recipient{{Email: "a@example.com"}}
+	message.Attachments = []*postage_app.Attachment{{FileName: "a.txt", ContentType: "text/plain", ContentBytes: []byte("12345")}}
+	if _, err := cl.SendMessage(message); err != nil {
+		t.Fatal(err)
+	}
+	cl.GetMetrics()
+
+	if v := testutil.ToFloat64(collector.calls.WithLabelValues("send_message", "ok")); v != 1 {
+		t.Log("send_message ok", v)
+		t.Fail()
+	}
+	if v := testutil.ToFloat64(collector.calls.WithLabelValues("get_metrics", "unauthorized")); v != 1 {
+		t.Log("get_metrics unauthorized", v)
+		t.Fail()
+	}
+	if v := testutil.ToFloat64(collector.retries.WithLabelValues("send_message")); v != 1 {
+		t.Log("retries", v)
+		t.Fail()
+	}
+	if v := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "attachment")); v != 5 {
+		t.Log("attachment bytes", v)
+		t.Fail()
+	}
+	if v := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "payload")); v == 0 {
+		t.Log("payload bytes", v)
+		t.Fail()
+	}
+
+	expected := `
+# HELP postageapp_client_queue_depth Messages waiting in a queue.
+# TYPE postageapp_client_queue_depth gauge
+postageapp_client_queue_depth{queue="outbox"} 3
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "postageapp_client_queue_depth"); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	if count := testutil.CollectAndCount(collector, "postageapp_client_call_duration_seconds"); count != 2 {
+		t.Log("duration series", count)
+		t.Fail()
+	}
+}
+
+func TestCollectorSkipsUnsentBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	message := new(postage_app.Message)
+	message.Text = "hello"
+	message.Recipients = []*postage_app.Recipient{{Email: "a@example.com"}}
+	message.Attachments = []*postage_app.Attachment{{FileName: "a.txt", ContentType: "text/plain", ContentBytes: []byte("12345")}}
+
+	collector := NewCollector()
+	sandboxed, _ := postage_app.NewClient("metrics-test-key", postage_app.WithSandbox(postage_app.NewSandbox(nil)),
+		postage_app.WithMiddleware(collector.Middleware()))
+	if _, err := sandboxed.SendMessage(message); err != nil {
+		t.Fatal(err)
+	}
+	if v := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "payload")); v != 0 {
+		t.Log("sandbox payload bytes", v)
+		t.Fail()
+	}
+	if v := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "attachment")); v != 0 {
+		t.Log("sandbox attachment bytes", v)
+		t.Fail()
+	}
+
+	collector = NewCollector()
+	breaker := postage_app.NewCircuitBreaker(1, 0, time.Hour)
+	guarded, _ := postage_app.NewClient("metrics-test-key", postage_app.WithBaseURL(server.URL),
+		postage_app.WithCircuitBreaker(breaker), postage_app.WithMiddleware(collector.Middleware()))
+	guarded.SendMessage(message)
+	if breaker.State() != postage_app.BreakerOpen {
+		t.Fatal("circuit did not open")
+	}
+	sent := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "payload"))
+	if sent == 0 {
+		t.Log("payload bytes", sent)
+		t.Fail()
+	}
+	guarded.SendMessage(message)
+	if v := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "payload")); v != sent {
+		t.Log("open circuit payload bytes", v, sent)
+		t.Fail()
+	}
+	if v := testutil.ToFloat64(collector.sentBytes.WithLabelValues("send_message", "attachment")); v != 5 {
+		t.Log("open circuit attachment bytes", v)
+		t.Fail()
+	}
+}
@@ -0,0 +1,136 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+var countColumns = []string{"recipients", "delivered", "failed", "pending", "opened", "clicked", "spammed", "hard_bounces", "soft_bounces"}
+
+func (counts *Counts) values() []int {
+	return []int{counts.Recipients, counts.Delivered, counts.Failed, counts.Pending, counts.Opened, counts.Clicked,
+		counts.Spammed, counts.HardBounces, counts.SoftBounces}
+}
+
+func sortedCounts(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if m[keys[i]] != m[keys[j]] {
+			return m[keys[i]] > m[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func (report *Report) Domains() []string {
+	domains := make([]string, 0, len(report.ByDomain))
+	for domain := range report.ByDomain {
+		domains = append(domains, domain)
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		a, b := report.ByDomain[domains[i]], report.ByDomain[domains[j]]
+		if a.Recipients != b.Recipients {
+			return a.Recipients > b.Recipients
+		}
+		return domains[i] < domains[j]
+	})
+	return domains
+}
+
+func (report *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+func (report *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"dimension", "key", "metric", "count"})
+	row := func(dimension string, key string, metric string, count int) {
+		writer.Write([]string{dimension, key, metric, strconv.Itoa(count)})
+	}
+
+	for i, value := range report.Totals.values() {
+		row("total", "", countColumns[i], value)
+	}
+	for _, domain := range report.Domains() {
+		for i, value := range report.ByDomain[domain].values() {
+			row("domain", domain, countColumns[i], value)
+		}
+	}
+	for _, status := range sortedCounts(report.ByStatus) {
+		row("status", status, "recipients", report.ByStatus[status])
+	}
+	for _, code := range sortedCounts(report.ByResultCode) {
+		row("result_code", code, "recipients", report.ByResultCode[code])
+	}
+	for _, reason := range sortedCounts(report.Reasons) {
+		row("reason", reason, "recipients", report.Reasons[reason])
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (report *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "messages: %d\n\n", report.Messages)
+
+	fmt.Fprint(tw, "domain\t")
+	for _, column := range countColumns {
+		fmt.Fprintf(tw, "%s\t", column)
+	}
+	fmt.Fprintln(tw)
+	printCounts := func(name string, counts *Counts) {
+		fmt.Fprintf(tw, "%s\t", name)
+		for _, value := range counts.values() {
+			fmt.Fprintf(tw, "%d\t", value)
+		}
+		fmt.Fprintln(tw)
+	}
+	for _, domain := range report.Domains() {
+		printCounts(domain, report.ByDomain[domain])
+	}
+	printCounts("total", &report.Totals)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	sections := []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"status", report.ByStatus},
+		{"result code", report.ByResultCode},
+		{"failure reason", report.Reasons},
+	}
+	for _, section := range sections {
+		if len(section.counts) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s:\n", section.title)
+		for _, key := range sortedCounts(section.counts) {
+			fmt.Fprintf(w, "  %6d  %s\n", section.counts[key], key)
+		}
+	}
+	if len(report.Errors) != 0 {
+		fmt.Fprintf(w, "\nerrors:\n")
+		uids := make([]string, 0, len(report.Errors))
+		for uid := range report.Errors {
+			uids = append(uids, uid)
+		}
+		sort.Strings(uids)
+		for _, uid := range uids {
+			fmt.Fprintf(w, "  %s: %s\n", uid, report.Errors[uid])
+		}
+	}
+	return nil
+}
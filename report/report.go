@@ -0,0 +1,154 @@
+package report
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+const DefaultConcurrency = 8
+
+type Fetcher interface {
+	GetMessageTransmissionsContext(ctx context.Context, uid string) (*postage_app.MessageTransmissionsResponse, error)
+}
+
+type Counts struct {
+	Recipients  int `json:"recipients"`
+	Delivered   int `json:"delivered"`
+	Failed      int `json:"failed"`
+	Pending     int `json:"pending"`
+	Opened      int `json:"opened"`
+	Clicked     int `json:"clicked"`
+	Spammed     int `json:"spammed"`
+	HardBounces int `json:"hard_bounces"`
+	SoftBounces int `json:"soft_bounces"`
+}
+
+type Report struct {
+	Messages     int                `json:"messages"`
+	Totals       Counts             `json:"totals"`
+	ByStatus     map[string]int     `json:"by_status"`
+	ByResultCode map[string]int     `json:"by_result_code"`
+	ByDomain     map[string]*Counts `json:"by_domain"`
+	Reasons      map[string]int     `json:"reasons"`
+	Errors       map[string]string  `json:"errors,omitempty"`
+}
+
+type Builder struct {
+	Client      Fetcher
+	Concurrency int
+}
+
+func NewBuilder(client Fetcher) *Builder {
+	builder := new(Builder)
+	builder.Client = client
+	builder.Concurrency = DefaultConcurrency
+	return builder
+}
+
+func NewReport() *Report {
+	report := new(Report)
+	report.ByStatus = make(map[string]int)
+	report.ByResultCode = make(map[string]int)
+	report.ByDomain = make(map[string]*Counts)
+	report.Reasons = make(map[string]int)
+	return report
+}
+
+func (builder *Builder) Build(ctx context.Context, uids []string) (*Report, error) {
+	report := NewReport()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, max(builder.Concurrency, 1))
+
+	seen := make(map[string]bool, len(uids))
+	for _, uid := range uids {
+		if seen[uid] {
+			continue
+		}
+		seen[uid] = true
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return report, ctx.Err()
+		}
+		wg.Add(1)
+		go func(uid string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			response, err := builder.Client.GetMessageTransmissionsContext(ctx, uid)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if report.Errors == nil {
+					report.Errors = make(map[string]string)
+				}
+				report.Errors[uid] = err.Error()
+				return
+			}
+			report.Add(response.Data)
+		}(uid)
+	}
+	wg.Wait()
+	return report, ctx.Err()
+}
+
+func (report *Report) Add(transmissions *postage_app.MessageTransmissions) {
+	if transmissions == nil {
+		return
+	}
+	report.Messages++
+	for email, transmission := range transmissions.Transmissions {
+		domain := strings.ToLower(email[strings.LastIndex(email, "@")+1:])
+		counts := report.ByDomain[domain]
+		if counts == nil {
+			counts = new(Counts)
+			report.ByDomain[domain] = counts
+		}
+		report.Totals.add(transmission)
+		counts.add(transmission)
+
+		report.ByStatus[string(transmission.Status)]++
+		if transmission.ResultCode != "" {
+			report.ByResultCode[transmission.ResultCode]++
+		}
+		if transmission.Status.IsFailure() && transmission.ResultMessage != "" {
+			report.Reasons[transmission.ResultMessage]++
+		}
+	}
+}
+
+func (counts *Counts) add(transmission *postage_app.MessageTransmission) {
+	counts.Recipients++
+	status := transmission.Status
+	switch {
+	case status.IsDelivered():
+		counts.Delivered++
+	case status.IsFailure():
+		counts.Failed++
+	default:
+		counts.Pending++
+	}
+	switch status {
+	case postage_app.TransmissionOpened:
+		counts.Opened++
+	case postage_app.TransmissionClicked:
+		counts.Opened++
+		counts.Clicked++
+	case postage_app.TransmissionSpammed:
+		counts.Spammed++
+	}
+	if status.IsFailure() {
+		switch transmission.Bounce() {
+		case postage_app.BounceHard:
+			counts.HardBounces++
+		case postage_app.BounceSoft:
+			counts.SoftBounces++
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+type fakeFetcher struct {
+	messages map[string]map[string]*postage_app.MessageTransmission
+	delay    time.Duration
+	active   atomic.Int32
+	peak     atomic.Int32
+	mu       sync.Mutex
+	calls    []string
+}
+
+func (fetcher *fakeFetcher) GetMessageTransmissionsContext(ctx context.Context, uid string) (*postage_app.MessageTransmissionsResponse, error) {
+	active := fetcher.active.Add(1)
+	defer fetcher.active.Add(-1)
+	for {
+		peak := fetcher.peak.Load()
+		if active <= peak || fetcher.peak.CompareAndSwap(peak, active) {
+			break
+		}
+	}
+	fetcher.mu.Lock()
+	fetcher.calls = append(fetcher.calls, uid)
+	fetcher.mu.Unlock()
+	time.Sleep(fetcher.delay)
+
+	transmissions, ok := fetcher.messages[uid]
+	if !ok {
+		return nil, errors.New("message not found")
+	}
+	response := new(postage_app.MessageTransmissionsResponse)
+	response.Response = &postage_app.Response{Status: "ok", Uid: uid}
+	response.Data = &postage_app.MessageTransmissions{Transmissions: transmissions}
+	return response, nil
+}
+
+func InitFetcher() *fakeFetcher {
+	fetcher := new(fakeFetcher)
+	fetcher.messages = map[string]map[string]*postage_app.MessageTransmission{
+		"a": {
+			"one@Example.com": {Status: postage_app.TransmissionCompleted, ResultCode: "250"},
+			"two@example.com": {Status: postage_app.TransmissionClicked, ResultCode: "250"},
+			"three@other.org": {Status: postage_app.TransmissionFailed, ResultCode: "550", ResultMessage: "mailbox unavailable"},
+		},
+		"b": {
+			"four@example.com": {Status: postage_app.TransmissionOpened, ResultCode: "250"},
+			"five@other.org":   {Status: postage_app.TransmissionFailed, ResultCode: "421", ResultMessage: "try again later"},
+			"six@other.org":    {Status: postage_app.TransmissionQueued},
+			"seven@other.org":  {Status: postage_app.TransmissionSpammed, ResultCode: "250"},
+		},
+	}
+	return fetcher
+}
+
+func TestBuildAggregates(t *testing.T) {
+	builder := NewBuilder(InitFetcher())
+	report, err := builder.Build(context.Background(), []string{"a", "b", "a", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Messages != 2 || report.Errors["missing"] != "message not found" || len(report.Errors) != 1 {
+		t.Log(report.Messages, report.Errors)
+		t.Fail()
+	}
+	want := Counts{Recipients: 7, Delivered: 4, Failed: 2, Pending: 1, Opened: 2, Clicked: 1, Spammed: 1, HardBounces: 1, SoftBounces: 1}
+	if report.Totals != want {
+		t.Log(report.Totals)
+		t.Fail()
+	}
+	for domain, counts := range report.ByDomain {
+		if counts.Recipients != counts.Delivered+counts.Failed+counts.Pending {
+			t.Log(domain, counts)
+			t.Fail()
+		}
+	}
+	if example := report.ByDomain["example.com"]; example == nil || example.Recipients != 3 || example.Delivered != 3 {
+		t.Log(example)
+		t.Fail()
+	}
+	if other := report.ByDomain["other.org"]; other == nil || other.Failed != 2 || other.HardBounces != 1 || other.Pending != 1 {
+		t.Log(other)
+		t.Fail()
+	}
+	if report.ByStatus["failed"] != 2 || report.ByResultCode["250"] != 4 || report.Reasons["mailbox unavailable"] != 1 {
+		t.Log(report.ByStatus, report.ByResultCode, report.Reasons)
+		t.Fail()
+	}
+}
+
+func TestBuildBoundsConcurrency(t *testing.T) {
+	fetcher := InitFetcher()
+	fetcher.delay = 10 * time.Millisecond
+	uids := make([]string, 20)
+	for i := range uids {
+		uids[i] = string(rune('a' + i))
+	}
+
+	builder := NewBuilder(fetcher)
+	builder.Concurrency = 3
+	if _, err := builder.Build(context.Background(), uids); err != nil {
+		t.Fatal(err)
+	}
+	if peak := fetcher.peak.Load(); peak > 3 || peak < 2 {
+		t.Log(peak)
+		t.Fail()
+	}
+	if len(fetcher.calls) != 20 {
+		t.Log(fetcher.calls)
+		t.Fail()
+	}
+}
+
+func TestBuildCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewBuilder(InitFetcher()).Build(ctx, []string{"a", "b"}); !errors.Is(err, context.Canceled) {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRender(t *testing.T) {
+	report, _ := NewBuilder(InitFetcher()).Build(context.Background(), []string{"a", "b"})
+
+	var buffer bytes.Buffer
+	if err := report.WriteJSON(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(Report)
+	if err := json.Unmarshal(buffer.Bytes(), decoded); err != nil || decoded.Totals != report.Totals {
+		t.Log(err, buffer.String())
+		t.Fail()
+	}
+
+	buffer.Reset()
+	if err := report.WriteCSV(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buffer).ReadAll()
+	if err != nil || records[0][0] != "dimension" || records[1][0] != "total" || records[1][3] != "7" {
+		t.Log(err, records)
+		t.Fail()
+	}
+
+	buffer.Reset()
+	if err := report.WriteText(&buffer); err != nil {
+		t.Fatal(err)
+	}
+	text := buffer.String()
+	if !strings.Contains(text, "example.com") || !strings.Contains(text, "mailbox unavailable") ||
+		strings.Index(text, "example.com") > strings.Index(text, "total") {
+		t.Log(text)
+		t.Fail()
+	}
+}
@@ -0,0 +1,40 @@
+package smtprelay
+
+import (
+	"context"
+	"errors"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+func ReplyForError(err error) (int, string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return 451, "4.4.7 PostageApp did not answer in time, try again later"
+	}
+	if errors.Is(err, context.Canceled) {
+		return 451, "4.3.2 Relay is shutting down, try again later"
+	}
+	switch e := err.(type) {
+	case *postage_app.ResponseParseError:
+		switch e.Message {
+		case "bad_request":
+			return 554, "5.6.0 Message rejected by PostageApp: bad_request"
+		case "precondition_failed":
+			return 554, "5.6.0 Message rejected by PostageApp: precondition_failed"
+		case "unauthorized":
+			return 451, "4.7.0 Relay is not authorized with PostageApp"
+		case "not_found":
+			return 550, "5.1.0 PostageApp resource not found"
+		default:
+			return 451, "4.3.0 PostageApp returned " + e.Message
+		}
+	case *postage_app.SuppressionError:
+		return 550, "5.1.1 Recipient address is suppressed"
+	case *postage_app.PolicyError:
+		return 550, "5.7.1 Recipient not allowed by relay policy"
+	case *postage_app.PostageResponseError:
+		return 451, "4.4.1 PostageApp is unreachable, try again later"
+	default:
+		return 451, "4.3.0 Temporary local error"
+	}
+}
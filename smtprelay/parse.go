@@ -0,0 +1,53 @@
+package smtprelay
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sort"
+	"strings"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+func parseMessage(r io.Reader, recipients []string) (*postage_app.Message, error) {
+	message, err := postage_app.ParseMIME(r)
+	if err != nil {
+		return nil, err
+	}
+	message.Recipients = envelopeRecipients(recipients)
+	message.Uid = transactionUid(message.Uid, message.Recipients)
+	return message, nil
+}
+
+func transactionUid(messageId string, recipients []*postage_app.Recipient) string {
+	if messageId == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		return hex.EncodeToString(b)
+	}
+	sorted := make([]string, len(recipients))
+	for i, recipient := range recipients {
+		sorted[i] = strings.ToLower(recipient.Email)
+	}
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(messageId + "\n" + strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:16])
+}
+
+func envelopeRecipients(addresses []string) []*postage_app.Recipient {
+	seen := make(map[string]bool)
+	var recipients []*postage_app.Recipient
+	for _, address := range addresses {
+		key := strings.ToLower(address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		recipient := new(postage_app.Recipient)
+		recipient.Email = address
+		recipients = append(recipients, recipient)
+	}
+	return recipients
+}
@@ -0,0 +1,257 @@
+package smtprelay
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+const (
+	DefaultAddr            = "127.0.0.1:2525"
+	DefaultMaxMessageBytes = 25 * 1024 * 1024
+	DefaultMaxRecipients   = 100
+	DefaultTimeout         = 5 * time.Minute
+	DefaultSendTimeout     = time.Minute
+)
+
+var ErrServerClosed = errors.New("smtprelay: server closed")
+
+type Sender interface {
+	SendMessageContext(ctx context.Context, message *postage_app.Message) (*postage_app.MessageResponse, error)
+}
+
+type Server struct {
+	Addr            string
+	Hostname        string
+	Client          Sender
+	Authenticate    func(username string, password string) bool
+	AllowedDomains  []string
+	TLSConfig       *tls.Config
+	MaxMessageBytes int64
+	MaxRecipients   int
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	SendTimeout     time.Duration
+	ErrorLog        *log.Logger
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func StaticAuth(username string, password string) func(string, string) bool {
+	return func(u string, p string) bool {
+		userOk := subtle.ConstantTimeCompare([]byte(u), []byte(username)) == 1
+		passOk := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		return userOk && passOk
+	}
+}
+
+func (server *Server) ListenAndServe() error {
+	addr := server.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return server.Serve(l)
+}
+
+func (server *Server) Serve(l net.Listener) error {
+	if !server.trackListener(l, true) {
+		l.Close()
+		return ErrServerClosed
+	}
+	defer server.trackListener(l, false)
+
+	var delay time.Duration
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if server.isClosed() {
+				return ErrServerClosed
+			}
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				if delay == 0 {
+					delay = 5 * time.Millisecond
+				} else if delay *= 2; delay > time.Second {
+					delay = time.Second
+				}
+				server.logf("smtprelay: accept error: %v; retrying in %v", err, delay)
+				time.Sleep(delay)
+				continue
+			}
+			return err
+		}
+		delay = 0
+
+		if !server.trackConn(conn, true) {
+			conn.Close()
+			return ErrServerClosed
+		}
+		server.wg.Add(1)
+		go func() {
+			defer server.wg.Done()
+			defer server.trackConn(conn, false)
+			newSession(server, conn).serve()
+		}()
+	}
+}
+
+func (server *Server) Close() error {
+	server.mu.Lock()
+	server.closed = true
+	var err error
+	for l := range server.listeners {
+		if cerr := l.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	for c := range server.conns {
+		c.Close()
+	}
+	if server.cancel != nil {
+		server.cancel()
+	}
+	server.mu.Unlock()
+	server.wg.Wait()
+	return err
+}
+
+func (server *Server) trackListener(l net.Listener, add bool) bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.listeners == nil {
+		server.listeners = make(map[net.Listener]struct{})
+	}
+	if add {
+		if server.closed {
+			return false
+		}
+		server.listeners[l] = struct{}{}
+	} else {
+		delete(server.listeners, l)
+	}
+	return true
+}
+
+func (server *Server) trackConn(c net.Conn, add bool) bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.conns == nil {
+		server.conns = make(map[net.Conn]struct{})
+	}
+	if add {
+		if server.closed {
+			return false
+		}
+		server.conns[c] = struct{}{}
+	} else {
+		delete(server.conns, c)
+	}
+	return true
+}
+
+func (server *Server) context() context.Context {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if server.ctx == nil {
+		server.ctx, server.cancel = context.WithCancel(context.Background())
+		if server.closed {
+			server.cancel()
+		}
+	}
+	return server.ctx
+}
+
+func (server *Server) isClosed() bool {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return server.closed
+}
+
+func (server *Server) hostname() string {
+	if server.Hostname != "" {
+		return server.Hostname
+	}
+	if name, err := os.Hostname(); err == nil {
+		return name
+	}
+	return "localhost"
+}
+
+func (server *Server) maxMessageBytes() int64 {
+	if server.MaxMessageBytes > 0 {
+		return server.MaxMessageBytes
+	}
+	return DefaultMaxMessageBytes
+}
+
+func (server *Server) maxRecipients() int {
+	if server.MaxRecipients > 0 {
+		return server.MaxRecipients
+	}
+	return DefaultMaxRecipients
+}
+
+func (server *Server) readTimeout() time.Duration {
+	if server.ReadTimeout > 0 {
+		return server.ReadTimeout
+	}
+	return DefaultTimeout
+}
+
+func (server *Server) writeTimeout() time.Duration {
+	if server.WriteTimeout > 0 {
+		return server.WriteTimeout
+	}
+	return DefaultTimeout
+}
+
+func (server *Server) sendTimeout() time.Duration {
+	if server.SendTimeout > 0 {
+		return server.SendTimeout
+	}
+	return DefaultSendTimeout
+}
+
+func (server *Server) domainAllowed(address string) bool {
+	if len(server.AllowedDomains) == 0 {
+		return true
+	}
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(address[at+1:])
+	for _, allowed := range server.AllowedDomains {
+		allowed = strings.ToLower(strings.TrimPrefix(allowed, "@"))
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (server *Server) logf(format string, args ...interface{}) {
+	if server.ErrorLog != nil {
+		server.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
@@ -0,0 +1,391 @@
+package smtprelay
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"net"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+type recordingSender struct {
+	mu       sync.Mutex
+	messages []*postage_app.Message
+	err      error
+	block    chan struct{}
+	ctxErr   error
+}
+
+func (sender *recordingSender) SendMessageContext(ctx context.Context, message *postage_app.Message) (*postage_app.MessageResponse, error) {
+	sender.mu.Lock()
+	sender.messages = append(sender.messages, message)
+	block := sender.block
+	sender.mu.Unlock()
+	if block != nil {
+		close(block)
+		<-ctx.Done()
+		sender.mu.Lock()
+		sender.ctxErr = ctx.Err()
+		sender.mu.Unlock()
+		return nil, &postage_app.PostageResponseError{Message: ctx.Err().Error(), InnerError: ctx.Err()}
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if sender.err != nil {
+		return nil, sender.err
+	}
+	response := new(postage_app.MessageResponse)
+	response.Response = &postage_app.Response{Status: "ok", Uid: message.Uid}
+	response.Data = &postage_app.MessageReceipt{Id: 42}
+	return response, nil
+}
+
+func startServer(t *testing.T, server *Server) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Hostname = "relay.test"
+	go server.Serve(l)
+	t.Cleanup(func() { server.Close() })
+	return l.Addr().String()
+}
+
+const multipartMessage = "From: Acme Widgets <widgets@acme.com>\r\n" +
+	"To: Alan Smithee <alan.smithee@gmail.com>\r\n" +
+	"Subject: =?UTF-8?Q?Thank_you_=E2=98=83?=\r\n" +
+	"Message-ID: <order-555@acme.com>\r\n" +
+	"X-Order-Id: 555\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Your order =3D shipped\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Your order shipped</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: text/plain; name=readme.txt\r\n" +
+	"Content-Disposition: attachment; filename=readme.txt\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"ZmlsZSBjb250ZW50cyEKCg==\r\n" +
+	"--outer--\r\n"
+
+func TestRelayForwardsMessage(t *testing.T) {
+	sender := new(recordingSender)
+	addr := startServer(t, &Server{Client: sender})
+
+	err := smtp.SendMail(addr, nil, "widgets@acme.com", []string{"alan.smithee@gmail.com", "rick.james@gmail.com"}, []byte(multipartMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sender.messages) != 1 {
+		t.Fatal(len(sender.messages))
+	}
+	message := sender.messages[0]
+
+	if message.Uid == "" || message.Uid == "order-555@acme.com" {
+		t.Log(message.Uid)
+		t.Fail()
+	}
+
+	if message.Subject != "Thank you ☃" {
+		t.Log(message.Subject)
+		t.Fail()
+	}
+
+	if message.From != "Acme Widgets <widgets@acme.com>" {
+		t.Log(message.From)
+		t.Fail()
+	}
+
+	if message.Text != "Your order = shipped" {
+		t.Log(message.Text)
+		t.Fail()
+	}
+
+	if message.Html != "<p>Your order shipped</p>" {
+		t.Log(message.Html)
+		t.Fail()
+	}
+
+	if len(message.Recipients) != 2 || message.Recipients[1].Email != "rick.james@gmail.com" {
+		t.Log(message.Recipients)
+		t.Fail()
+	}
+
+	if message.Headers["X-Order-Id"] != "555" {
+		t.Log(message.Headers)
+		t.Fail()
+	}
+
+	if _, ok := message.Headers["To"]; ok {
+		t.Log(message.Headers)
+		t.Fail()
+	}
+
+	if len(message.Attachments) != 1 || string(message.Attachments[0].ContentBytes) != "file contents!\n\n" {
+		t.Log(message.Attachments)
+		t.Fail()
+	}
+}
+
+func TestRelayUidPerTransaction(t *testing.T) {
+	sender := new(recordingSender)
+	addr := startServer(t, &Server{Client: sender, MaxRecipients: 1})
+
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	for _, batch := range [][]string{{"alan.smithee@gmail.com"}, {"rick.james@gmail.com"}, {"Alan.Smithee@gmail.com"}} {
+		client.Mail("widgets@acme.com")
+		client.Rcpt(batch[0])
+		w, err := client.Data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(multipartMessage))
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sender.messages) != 3 {
+		t.Fatal(len(sender.messages))
+	}
+	if sender.messages[0].Uid == sender.messages[1].Uid || sender.messages[0].Uid != sender.messages[2].Uid {
+		t.Log("split transactions need distinct uids, resends of the same batch the same one",
+			sender.messages[0].Uid, sender.messages[1].Uid, sender.messages[2].Uid)
+		t.Fail()
+	}
+}
+
+func TestRelayRequiresAuth(t *testing.T) {
+	sender := new(recordingSender)
+	addr := startServer(t, &Server{Client: sender, Authenticate: StaticAuth("relay", "secret")})
+
+	err := smtp.SendMail(addr, nil, "widgets@acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err == nil || !strings.HasPrefix(err.Error(), "530") {
+		t.Log(err)
+		t.Fail()
+	}
+
+	err = smtp.SendMail(addr, smtp.PlainAuth("", "relay", "wrong", "127.0.0.1"), "widgets@acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err == nil || !strings.HasPrefix(err.Error(), "535") {
+		t.Log(err)
+		t.Fail()
+	}
+
+	err = smtp.SendMail(addr, smtp.PlainAuth("", "relay", "secret", "127.0.0.1"), "widgets@acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+
+	if len(sender.messages) != 1 {
+		t.Log(len(sender.messages))
+		t.Fail()
+	}
+}
+
+func TestRelayRequiresTLSForAuth(t *testing.T) {
+	certs := httptest.NewTLSServer(nil)
+	defer certs.Close()
+	sender := new(recordingSender)
+	addr := startServer(t, &Server{Client: sender, Authenticate: StaticAuth("relay", "secret"), TLSConfig: certs.TLS})
+
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	client.Hello("localhost")
+	if ok, _ := client.Extension("AUTH"); ok {
+		t.Log("AUTH should not be advertised before STARTTLS")
+		t.Fail()
+	}
+	id, _ := client.Text.Cmd("AUTH PLAIN %s", base64.StdEncoding.EncodeToString([]byte("\x00relay\x00secret")))
+	client.Text.StartResponse(id)
+	code, _, _ := client.Text.ReadResponse(538)
+	client.Text.EndResponse(id)
+	if code != 538 {
+		t.Log(code)
+		t.Fail()
+	}
+
+	if err := client.StartTLS(&tls.Config{InsecureSkipVerify: true}); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := client.Extension("AUTH"); !ok {
+		t.Log("AUTH should be advertised after STARTTLS")
+		t.Fail()
+	}
+	if err := client.Auth(smtp.PlainAuth("", "relay", "secret", "127.0.0.1")); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRelayRejectsSenderDomain(t *testing.T) {
+	sender := new(recordingSender)
+	addr := startServer(t, &Server{Client: sender, AllowedDomains: []string{"acme.com"}})
+
+	err := smtp.SendMail(addr, nil, "someone@example.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err == nil || !strings.HasPrefix(err.Error(), "550") {
+		t.Log(err)
+		t.Fail()
+	}
+
+	err = smtp.SendMail(addr, nil, "orders@mail.acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRelayRejectsOversizedMessage(t *testing.T) {
+	sender := new(recordingSender)
+	addr := startServer(t, &Server{Client: sender, MaxMessageBytes: 50, ReadTimeout: 2 * time.Second})
+
+	client, err := smtp.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	start := time.Now()
+	client.Mail("widgets@acme.com")
+	client.Rcpt("alan.smithee@gmail.com")
+	w, err := client.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(multipartMessage))
+	err = w.Close()
+	if err == nil || !strings.HasPrefix(err.Error(), "552") || time.Since(start) > time.Second {
+		t.Log(err, time.Since(start))
+		t.Fail()
+	}
+
+	if err := client.Reset(); err != nil {
+		t.Log("session should remain usable", err)
+		t.Fail()
+	}
+	if len(sender.messages) != 0 {
+		t.Log(len(sender.messages))
+		t.Fail()
+	}
+}
+
+func TestRelayMapsApiErrors(t *testing.T) {
+	sender := new(recordingSender)
+	sender.err = &postage_app.ResponseParseError{Message: "bad_request"}
+	addr := startServer(t, &Server{Client: sender})
+
+	err := smtp.SendMail(addr, nil, "widgets@acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err == nil || !strings.HasPrefix(err.Error(), "554") {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestRelaySendTimeout(t *testing.T) {
+	sender := new(recordingSender)
+	sender.block = make(chan struct{})
+	addr := startServer(t, &Server{Client: sender, SendTimeout: 50 * time.Millisecond})
+
+	err := smtp.SendMail(addr, nil, "widgets@acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	if err == nil || !strings.HasPrefix(err.Error(), "451") || !strings.Contains(err.Error(), "4.4.7") {
+		t.Log(err)
+		t.Fail()
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if sender.ctxErr != context.DeadlineExceeded {
+		t.Log(sender.ctxErr)
+		t.Fail()
+	}
+}
+
+func TestRelayCloseCancelsSend(t *testing.T) {
+	sender := new(recordingSender)
+	sender.block = make(chan struct{})
+	server := &Server{Client: sender, SendTimeout: time.Hour}
+	addr := startServer(t, server)
+
+	go smtp.SendMail(addr, nil, "widgets@acme.com", []string{"alan.smithee@gmail.com"}, []byte(multipartMessage))
+	<-sender.block
+
+	closed := make(chan struct{})
+	go func() {
+		server.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close waited for a hung send")
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	if sender.ctxErr != context.Canceled {
+		t.Log(sender.ctxErr)
+		t.Fail()
+	}
+}
+
+func TestReplyForError(t *testing.T) {
+	code, _ := ReplyForError(&postage_app.PostageResponseError{Message: "connection refused"})
+	if code != 451 {
+		t.Log(code)
+		t.Fail()
+	}
+
+	code, _ = ReplyForError(&postage_app.ResponseParseError{Message: "precondition_failed"})
+	if code != 554 {
+		t.Log(code)
+		t.Fail()
+	}
+
+	code, message := ReplyForError(&postage_app.SuppressionError{})
+	if code != 550 || !strings.HasPrefix(message, "5.1.1") {
+		t.Log(code, message)
+		t.Fail()
+	}
+
+	code, message = ReplyForError(&postage_app.PostageResponseError{Message: "deadline", InnerError: context.DeadlineExceeded})
+	if code != 451 || !strings.HasPrefix(message, "4.4.7") {
+		t.Log(code, message)
+		t.Fail()
+	}
+
+	code, message = ReplyForError(&postage_app.PolicyError{Message: "recipient_policy: all recipients blocked"})
+	if code != 550 || !strings.HasPrefix(message, "5.7.1") {
+		t.Log(code, message)
+		t.Fail()
+	}
+}
@@ -0,0 +1,398 @@
+package smtprelay
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+type session struct {
+	server        *Server
+	conn          net.Conn
+	text          *textproto.Conn
+	helo          string
+	tls           bool
+	authenticated bool
+	from          string
+	recipients    []string
+}
+
+func newSession(server *Server, conn net.Conn) *session {
+	s := new(session)
+	s.server = server
+	s.conn = conn
+	s.text = textproto.NewConn(conn)
+	_, s.tls = conn.(*tls.Conn)
+	return s
+}
+
+func (s *session) serve() {
+	defer s.text.Close()
+
+	if s.server.Client == nil {
+		s.reply(421, "4.3.0 Relay is not configured")
+		return
+	}
+
+	s.reply(220, fmt.Sprintf("%s ESMTP PostageApp relay", s.server.hostname()))
+
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(s.server.readTimeout()))
+		line, err := s.text.ReadLine()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				s.reply(421, "4.4.2 Idle timeout, closing connection")
+			}
+			return
+		}
+
+		verb, arg := splitCommand(line)
+		switch verb {
+		case "HELO":
+			s.handleHelo(arg, false)
+		case "EHLO":
+			s.handleHelo(arg, true)
+		case "STARTTLS":
+			if !s.handleStartTLS() {
+				return
+			}
+		case "AUTH":
+			s.handleAuth(arg)
+		case "MAIL":
+			s.handleMail(arg)
+		case "RCPT":
+			s.handleRcpt(arg)
+		case "DATA":
+			if !s.handleData() {
+				return
+			}
+		case "RSET":
+			s.reset()
+			s.reply(250, "2.0.0 Ok")
+		case "NOOP":
+			s.reply(250, "2.0.0 Ok")
+		case "VRFY":
+			s.reply(252, "2.5.2 Cannot VRFY user")
+		case "QUIT":
+			s.reply(221, "2.0.0 Bye")
+			return
+		default:
+			s.reply(502, "5.5.2 Command not recognized")
+		}
+	}
+}
+
+func splitCommand(line string) (string, string) {
+	line = strings.TrimRight(line, " \t")
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		return strings.ToUpper(line[:i]), strings.TrimSpace(line[i+1:])
+	}
+	return strings.ToUpper(line), ""
+}
+
+func (s *session) reply(code int, message string) {
+	s.conn.SetWriteDeadline(time.Now().Add(s.server.writeTimeout()))
+	s.text.PrintfLine("%d %s", code, message)
+}
+
+func (s *session) replyLines(code int, lines []string) {
+	s.conn.SetWriteDeadline(time.Now().Add(s.server.writeTimeout()))
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		s.text.PrintfLine("%d%s%s", code, separator, line)
+	}
+}
+
+func (s *session) reset() {
+	s.from = ""
+	s.recipients = nil
+}
+
+func (s *session) authRequired() bool {
+	return s.server.Authenticate != nil && !s.authenticated
+}
+
+func (s *session) authAllowed() bool {
+	return s.server.Authenticate != nil && (s.server.TLSConfig == nil || s.tls)
+}
+
+func (s *session) handleHelo(arg string, extended bool) {
+	if arg == "" {
+		s.reply(501, "5.5.4 Domain name required")
+		return
+	}
+	s.helo = arg
+	s.reset()
+
+	if !extended {
+		s.reply(250, s.server.hostname())
+		return
+	}
+
+	lines := []string{
+		s.server.hostname(),
+		"PIPELINING",
+		"8BITMIME",
+		"ENHANCEDSTATUSCODES",
+		fmt.Sprintf("SIZE %d", s.server.maxMessageBytes()),
+	}
+	if s.server.TLSConfig != nil && !s.tls {
+		lines = append(lines, "STARTTLS")
+	}
+	if s.authAllowed() {
+		lines = append(lines, "AUTH PLAIN LOGIN")
+	}
+	s.replyLines(250, lines)
+}
+
+func (s *session) handleStartTLS() bool {
+	if s.server.TLSConfig == nil || s.tls {
+		s.reply(502, "5.5.1 STARTTLS not available")
+		return true
+	}
+	s.reply(220, "2.0.0 Ready to start TLS")
+
+	conn := tls.Server(s.conn, s.server.TLSConfig)
+	conn.SetDeadline(time.Now().Add(s.server.readTimeout()))
+	if err := conn.Handshake(); err != nil {
+		s.server.logf("smtprelay: TLS handshake from %s failed: %v", s.conn.RemoteAddr(), err)
+		return false
+	}
+	s.conn = conn
+	s.text = textproto.NewConn(conn)
+	s.tls = true
+	s.helo = ""
+	s.authenticated = false
+	s.reset()
+	return true
+}
+
+func (s *session) handleAuth(arg string) {
+	if s.server.Authenticate == nil {
+		s.reply(502, "5.5.1 AUTH not available")
+		return
+	}
+	if !s.authAllowed() {
+		s.reply(538, "5.7.11 Encryption required for requested authentication mechanism")
+		return
+	}
+	if s.helo == "" {
+		s.reply(503, "5.5.1 Send EHLO first")
+		return
+	}
+	if s.authenticated {
+		s.reply(503, "5.5.1 Already authenticated")
+		return
+	}
+
+	mechanism, initial := splitCommand(arg)
+	var username, password string
+	var err error
+	switch mechanism {
+	case "PLAIN":
+		username, password, err = s.authPlain(initial)
+	case "LOGIN":
+		username, password, err = s.authLogin(initial)
+	default:
+		s.reply(504, "5.5.4 Unrecognized authentication type")
+		return
+	}
+	if err != nil {
+		s.reply(501, "5.5.2 Cannot decode response")
+		return
+	}
+
+	if !s.server.Authenticate(username, password) {
+		s.reply(535, "5.7.8 Authentication credentials invalid")
+		return
+	}
+	s.authenticated = true
+	s.reply(235, "2.7.0 Authentication successful")
+}
+
+func (s *session) challenge(prompt string) (string, error) {
+	s.reply(334, prompt)
+	s.conn.SetReadDeadline(time.Now().Add(s.server.readTimeout()))
+	line, err := s.text.ReadLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "*" {
+		return "", fmt.Errorf("authentication cancelled")
+	}
+	return line, nil
+}
+
+func (s *session) authPlain(initial string) (string, string, error) {
+	var err error
+	if initial == "" {
+		if initial, err = s.challenge(""); err != nil {
+			return "", "", err
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return "", "", err
+	}
+	parts := bytes.Split(decoded, []byte{0})
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("malformed PLAIN response")
+	}
+	return string(parts[1]), string(parts[2]), nil
+}
+
+func (s *session) authLogin(initial string) (string, string, error) {
+	var err error
+	if initial == "" {
+		if initial, err = s.challenge(base64.StdEncoding.EncodeToString([]byte("Username:"))); err != nil {
+			return "", "", err
+		}
+	}
+	username, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return "", "", err
+	}
+	response, err := s.challenge(base64.StdEncoding.EncodeToString([]byte("Password:")))
+	if err != nil {
+		return "", "", err
+	}
+	password, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		return "", "", err
+	}
+	return string(username), string(password), nil
+}
+
+func parsePath(arg string, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	arg = strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(arg, "<") {
+		return "", false
+	}
+	end := strings.IndexByte(arg, '>')
+	if end < 0 {
+		return "", false
+	}
+	return arg[1:end], true
+}
+
+func (s *session) handleMail(arg string) {
+	if s.helo == "" {
+		s.reply(503, "5.5.1 Send HELO/EHLO first")
+		return
+	}
+	if s.authRequired() {
+		s.reply(530, "5.7.0 Authentication required")
+		return
+	}
+	if s.from != "" {
+		s.reply(503, "5.5.1 Sender already specified")
+		return
+	}
+	from, ok := parsePath(arg, "FROM:")
+	if !ok {
+		s.reply(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return
+	}
+	if from == "" {
+		s.reply(550, "5.7.1 Null sender not accepted")
+		return
+	}
+	if _, err := mail.ParseAddress(from); err != nil {
+		s.reply(553, "5.1.7 Invalid sender address")
+		return
+	}
+	if !s.server.domainAllowed(from) {
+		s.reply(550, "5.7.1 Sender domain not allowed")
+		return
+	}
+	s.from = from
+	s.reply(250, "2.1.0 Ok")
+}
+
+func (s *session) handleRcpt(arg string) {
+	if s.from == "" {
+		s.reply(503, "5.5.1 Need MAIL before RCPT")
+		return
+	}
+	to, ok := parsePath(arg, "TO:")
+	if !ok || to == "" {
+		s.reply(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return
+	}
+	if _, err := mail.ParseAddress(to); err != nil {
+		s.reply(553, "5.1.3 Invalid recipient address")
+		return
+	}
+	if len(s.recipients) >= s.server.maxRecipients() {
+		s.reply(452, "4.5.3 Too many recipients")
+		return
+	}
+	s.recipients = append(s.recipients, to)
+	s.reply(250, "2.1.5 Ok")
+}
+
+func (s *session) handleData() bool {
+	if len(s.recipients) == 0 {
+		s.reply(503, "5.5.1 Need RCPT before DATA")
+		return true
+	}
+	s.reply(354, "End data with <CR><LF>.<CR><LF>")
+
+	s.conn.SetReadDeadline(time.Now().Add(s.server.readTimeout()))
+	limit := s.server.maxMessageBytes()
+	dr := s.text.DotReader()
+	data, err := ioutil.ReadAll(io.LimitReader(dr, limit+1))
+	if err != nil {
+		return false
+	}
+	if int64(len(data)) > limit {
+		if _, err := io.Copy(ioutil.Discard, dr); err != nil {
+			return false
+		}
+		s.reset()
+		s.reply(552, "5.3.4 Message size exceeds fixed limit")
+		return true
+	}
+
+	code, message := s.deliver(data)
+	s.reset()
+	s.reply(code, message)
+	return true
+}
+
+func (s *session) deliver(data []byte) (int, string) {
+	message, err := parseMessage(bytes.NewReader(data), s.recipients)
+	if err != nil {
+		return 554, "5.6.0 Message could not be parsed"
+	}
+	if message.From == "" {
+		message.From = s.from
+	}
+
+	ctx, cancel := context.WithTimeout(s.server.context(), s.server.sendTimeout())
+	defer cancel()
+	response, err := s.server.Client.SendMessageContext(ctx, message)
+	if err != nil {
+		s.server.logf("smtprelay: delivery of %s from %s failed: %v", message.Uid, s.from, err)
+		return ReplyForError(err)
+	}
+	if response != nil && response.Data != nil {
+		return 250, fmt.Sprintf("2.0.0 Ok: queued as %d", response.Data.Id)
+	}
+	return 250, "2.0.0 Ok: queued"
+}
@@ -0,0 +1,122 @@
+package tracing
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+const ScopeName = "github.com/postageapp/postageapp-go/tracing"
+
+const (
+	AttributeUid             = attribute.Key("postageapp.uid")
+	AttributeRecipientCount  = attribute.Key("postageapp.recipient_count")
+	AttributeAttachmentBytes = attribute.Key("postageapp.attachment_bytes")
+	AttributeApiStatus       = attribute.Key("postageapp.api_status")
+	AttributeRetryCount      = attribute.Key("postageapp.retry_count")
+	AttributeHttpStatus      = attribute.Key("http.response.status_code")
+)
+
+type Option func(tracer *tracer)
+
+type tracer struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(tracer *tracer) {
+		tracer.provider = provider
+	}
+}
+
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(tracer *tracer) {
+		tracer.propagator = propagator
+	}
+}
+
+func Middleware(opts ...Option) postage_app.Middleware {
+	config := new(tracer)
+	for _, opt := range opts {
+		opt(config)
+	}
+	if config.provider == nil {
+		config.provider = otel.GetTracerProvider()
+	}
+	if config.propagator == nil {
+		config.propagator = otel.GetTextMapPropagator()
+	}
+	spans := config.provider.Tracer(ScopeName)
+
+	return func(next postage_app.Handler) postage_app.Handler {
+		return func(call *postage_app.Call) (map[string]interface{}, error) {
+			ctx, span := spans.Start(call.Context, call.Endpoint,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(requestAttributes(call.Request)...))
+			defer span.End()
+
+			call.Context = ctx
+			config.propagator.Inject(ctx, propagation.HeaderCarrier(call.Header))
+			onRetry := call.OnRetry
+			call.OnRetry = func(attempt int, delay time.Duration, err error) {
+				attrs := []attribute.KeyValue{attribute.Int("attempt", attempt), attribute.String("delay", delay.String())}
+				if err != nil {
+					attrs = append(attrs, attribute.String("error", err.Error()))
+				}
+				span.AddEvent("retry", trace.WithAttributes(attrs...))
+				if onRetry != nil {
+					onRetry(attempt, delay, err)
+				}
+			}
+
+			m, err := next(call)
+
+			span.SetAttributes(AttributeRetryCount.Int(max(call.Attempts-1, 0)))
+			if call.HTTPResponse != nil {
+				span.SetAttributes(AttributeHttpStatus.Int(call.HTTPResponse.StatusCode))
+			}
+			if status := apiStatus(m); status != "" {
+				span.SetAttributes(AttributeApiStatus.String(status))
+				if status != "ok" {
+					span.SetStatus(codes.Error, status)
+				}
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			return m, err
+		}
+	}
+}
+
+func requestAttributes(request interface{}) []attribute.KeyValue {
+	switch request := request.(type) {
+	case *postage_app.Message:
+		attachmentBytes := 0
+		for _, attachment := range request.Attachments {
+			attachmentBytes += len(attachment.ContentBytes)
+		}
+		return []attribute.KeyValue{
+			AttributeUid.String(request.Uid),
+			AttributeRecipientCount.Int(len(request.Recipients)),
+			AttributeAttachmentBytes.Int(attachmentBytes),
+		}
+	case string:
+		return []attribute.KeyValue{AttributeUid.String(request)}
+	}
+	return nil
+}
+
+func apiStatus(m map[string]interface{}) string {
+	response, _ := m["response"].(map[string]interface{})
+	status, _ := response["status"].(string)
+	return status
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	postage_app "github.com/postageapp/postageapp-go"
+)
+
+func InitTracing(t *testing.T, handler http.HandlerFunc) (*postage_app.Client, *tracetest.InMemoryExporter, *sdktrace.TracerProvider) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	cl, err := postage_app.NewClient("tracing-test-key",
+		postage_app.WithBaseURL(server.URL),
+		postage_app.WithRetry(3, time.Millisecond, time.Millisecond),
+		postage_app.WithMiddleware(Middleware(WithTracerProvider(provider), WithPropagator(propagation.TraceContext{}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cl, exporter, provider
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	values := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestSendMessageSpan(t *testing.T) {
+	var calls atomic.Int32
+	var traceparent atomic.Value
+	cl, exporter, provider := InitTracing(t, func(w http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("Traceparent"))
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"response":{"status":"ok","uid":"abc"},"data":{"message":{"id":1,"url":""}}}`))
+	})
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "handler")
+	message := new(postage_app.Message)
+	message.Uid = "abc"
+	message.Text = "hello"
+	message.Recipients = []*postage_app.Recipient{{Email: "a@example.com"}, {Email: "b@example.com"}}
+	message.Attachments = []*postage_app.Attachment{{FileName: "a.txt", ContentType: "text/plain", ContentBytes: []byte("12345")}}
+	if _, err := cl.SendMessageContext(ctx, message); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatal(spans)
+	}
+	span := spans[0]
+	if span.Name != "send_message" || span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Log(span.Name, span.Parent)
+		t.Fail()
+	}
+
+	values := attributes(span)
+	if values[AttributeUid].AsString() != "abc" || values[AttributeRecipientCount].AsInt64() != 2 ||
+		values[AttributeAttachmentBytes].AsInt64() != 5 || values[AttributeApiStatus].AsString() != "ok" ||
+		values[AttributeRetryCount].AsInt64() != 1 || values[AttributeHttpStatus].AsInt64() != 200 {
+		t.Log(span.Attributes)
+		t.Fail()
+	}
+	if len(span.Events) != 1 || span.Events[0].Name != "retry" {
+		t.Log(span.Events)
+		t.Fail()
+	}
+	if header, _ := traceparent.Load().(string); header == "" {
+		t.Log("traceparent was not propagated")
+		t.Fail()
+	}
+}
+
+func TestApiErrorSpan(t *testing.T) {
+	cl, exporter, _ := InitTracing(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":{"status":"unauthorized"}}`))
+	})
+
+	if _, err := cl.GetMetrics(); err == nil {
+		t.Fail()
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "get_metrics" || spans[0].Status.Code != codes.Error {
+		t.Log(spans)
+		t.Fail()
+	}
+	if attributes(spans[0])[AttributeApiStatus].AsString() != "unauthorized" {
+		t.Log(spans[0].Attributes)
+		t.Fail()
+	}
+}